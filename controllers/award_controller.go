@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/rules"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+)
+
+// awardWithCount is an award along with the number of users holding it
+type awardWithCount struct {
+	models.Award
+	HolderCount int64 `json:"holder_count"`
+}
+
+// awardQualifier is a user who would qualify for an award in a dry run
+type awardQualifier struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// GetAwards lists every award with the number of users holding it
+func GetAwards(c *gin.Context) {
+	query := database.DB.Table("awards").
+		Select("awards.*, COUNT(user_badges.user_id) AS holder_count").
+		Joins("LEFT JOIN user_badges ON user_badges.award_id = awards.id").
+		Group("awards.id")
+
+	page, err := pagination.Paginate(c, query, pagination.Key[awardWithCount]{
+		Column:   "awards.name",
+		Kind:     pagination.KindString,
+		IDColumn: "awards.id",
+		Value:    func(a awardWithCount) interface{} { return a.Name },
+		ID:       func(a awardWithCount) uint { return a.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve awards")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetAward retrieves a single award
+func GetAward(c *gin.Context) {
+	var award models.Award
+	if err := database.DB.First(&award, c.Param("awardId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Award not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, award)
+}
+
+// CreateAward adds an award and grants it to everyone who already qualifies (requires admin permission)
+func CreateAward(c *gin.Context) {
+	var input struct {
+		Name        string          `json:"name" binding:"required"`
+		Description string          `json:"description"`
+		Points      int             `json:"points"`   // Threshold, only used without criteria
+		Criteria    *rules.Criteria `json:"criteria"` // Rules deciding who earns the award
+		IconURL     string          `json:"icon_url"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	award := models.Award{
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+		Points:      input.Points,
+		Criteria:    input.Criteria,
+		IconURL:     input.IconURL,
+	}
+	if msg := validateAward(award); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var changes services.AwardChanges
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&award).Error; err != nil {
+			return err
+		}
+		var err error
+		changes, err = services.ReevaluateAward(tx, award.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create award"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"award":   award,
+		"granted": changes.Granted,
+	})
+}
+
+// UpdateAward partially updates an award, everyone is checked again when its threshold or criteria
+// change (requires admin permission)
+func UpdateAward(c *gin.Context) {
+	var input struct {
+		Name        *string                        `json:"name"`
+		Description *string                        `json:"description"`
+		Points      *int                           `json:"points"`
+		Criteria    utils.Nullable[rules.Criteria] `json:"criteria"` // null goes back to the points threshold
+		IconURL     *string                        `json:"icon_url"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var award models.Award
+	var changes services.AwardChanges
+	var validationMsg string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&award, c.Param("awardId")).Error; err != nil {
+			return err
+		}
+
+		if input.Name != nil {
+			award.Name = strings.TrimSpace(*input.Name)
+		}
+		if input.Description != nil {
+			award.Description = *input.Description
+		}
+		if input.Points != nil {
+			award.Points = *input.Points
+		}
+		award.Criteria = input.Criteria.Or(award.Criteria)
+		if input.IconURL != nil && *input.IconURL != award.IconURL {
+			award.IconURL = *input.IconURL
+			award.IconThumbnailURL = "" // The thumbnail belonged to the previous icon
+		}
+		if validationMsg = validateAward(award); validationMsg != "" {
+			return errAwardInvalid
+		}
+
+		if err := tx.Omit("Events").Save(&award).Error; err != nil {
+			return err
+		}
+		if input.Points == nil && !input.Criteria.Set {
+			return nil
+		}
+		var err error
+		changes, err = services.ReevaluateAward(tx, award.ID)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Award not found"})
+		return
+	}
+	if errors.Is(err, errAwardInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationMsg})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update award"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"award":   award,
+		"granted": changes.Granted,
+		"revoked": changes.Revoked,
+	})
+}
+
+// DeleteAward removes an award from everyone holding it, its events and templates (requires admin permission)
+func DeleteAward(c *gin.Context) {
+	awardID := c.Param("awardId")
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"user_badges", "event_awards", "event_template_awards"} {
+			if err := tx.Table(table).Where("award_id = ?", awardID).Delete(nil).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Delete(&models.Award{}, awardID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Award not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete award"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Award deleted"})
+}
+
+// DryRunAward shows who meets award criteria without granting anything. The criteria come from the
+// request or from award_id, and with award_id the response also lists whose badges would change
+// (requires admin permission)
+func DryRunAward(c *gin.Context) {
+	var input struct {
+		AwardID  *uint           `json:"award_id"`
+		Criteria *rules.Criteria `json:"criteria"` // Defaults to the award's criteria
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var award models.Award
+	if input.AwardID != nil {
+		if err := database.DB.First(&award, *input.AwardID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Award not found"})
+			return
+		}
+		if input.Criteria == nil {
+			input.Criteria = award.Criteria
+		}
+	}
+	if input.Criteria == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "criteria or an award with criteria is required"})
+		return
+	}
+	if err := input.Criteria.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid criteria: " + err.Error()})
+		return
+	}
+
+	qualifyingIDs, err := services.QualifyingUsers(database.DB, *input.Criteria)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate criteria"})
+		return
+	}
+	qualifying := []awardQualifier{}
+	if len(qualifyingIDs) > 0 {
+		if err := database.DB.Model(&models.User{}).Where("id IN ?", qualifyingIDs).Order("name").
+			Find(&qualifying).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+			return
+		}
+	}
+	response := gin.H{
+		"criteria":         input.Criteria,
+		"qualifying_count": len(qualifying),
+		"qualifying":       qualifying,
+	}
+
+	// Compare with the current holders, only automatically granted badges would be revoked
+	if input.AwardID != nil {
+		var holders []models.UserBadge
+		if err := database.DB.Where("award_id = ?", award.ID).Find(&holders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve award holders"})
+			return
+		}
+		qualifies := make(map[uint]bool, len(qualifyingIDs))
+		for _, userID := range qualifyingIDs {
+			qualifies[userID] = true
+		}
+		holds := make(map[uint]bool, len(holders))
+		wouldRevoke := []uint{}
+		for _, holder := range holders {
+			holds[holder.UserID] = true
+			automatic := holder.Source == string(models.BadgeSourceThreshold) ||
+				holder.Source == string(models.BadgeSourceCriteria)
+			if automatic && !qualifies[holder.UserID] && !services.AwardsPermanent() {
+				wouldRevoke = append(wouldRevoke, holder.UserID)
+			}
+		}
+		wouldGrant := []uint{}
+		for _, userID := range qualifyingIDs {
+			if !holds[userID] {
+				wouldGrant = append(wouldGrant, userID)
+			}
+		}
+		response["would_grant"] = wouldGrant
+		response["would_revoke"] = wouldRevoke
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+var errAwardInvalid = errors.New("invalid award")
+
+// Helper function to validate an award, returns an error message or ""
+func validateAward(award models.Award) string {
+	if award.Name == "" {
+		return "name must not be empty"
+	}
+	if award.Points < 0 {
+		return "points must not be negative"
+	}
+	if award.Criteria != nil {
+		if err := award.Criteria.Validate(); err != nil {
+			return "Invalid criteria: " + err.Error()
+		}
+	}
+	return ""
+}
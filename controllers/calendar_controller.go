@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/utils"
+)
+
+// Past events stay in the feeds for this long so calendars don't drop them right after they happen
+const calendarFeedLookback = 30 * 24 * time.Hour
+
+// GetCalendarFeeds returns the current user's feed URLs, creating their calendar token if needed
+func GetCalendarFeeds(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.CalendarToken == "" {
+		if err := rotateCalendarToken(&user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar token"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, calendarFeedURLs(user.CalendarToken))
+}
+
+// RotateCalendarToken invalidates the current user's feed URLs and issues new ones
+func RotateCalendarToken(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := rotateCalendarToken(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate calendar token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarFeedURLs(user.CalendarToken))
+}
+
+// GetEventsFeed serves the iCalendar feed of all upcoming events (authenticated by calendar token)
+func GetEventsFeed(c *gin.Context) {
+	if _, ok := userByCalendarToken(c); !ok {
+		return
+	}
+
+	var events []models.Event
+	err := database.DB.
+		Where("status <> ? AND start_time IS NOT NULL AND start_time >= ?", models.EventDraft, time.Now().Add(-calendarFeedLookback)).
+		Order("start_time asc").
+		Find(&events).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	entries := make([]utils.CalendarEvent, 0, len(events))
+	for _, event := range events {
+		entries = append(entries, calendarEntry(event, "CONFIRMED"))
+	}
+	writeCalendar(c, "EcoCampus events", "events.ics", entries)
+}
+
+// GetPersonalFeed serves the iCalendar feed of the events a user RSVP'd to (authenticated by calendar token)
+func GetPersonalFeed(c *gin.Context) {
+	user, ok := userByCalendarToken(c)
+	if !ok {
+		return
+	}
+
+	var rows []struct {
+		models.Event
+		RSVPStatus string
+	}
+	err := database.DB.Model(&models.Event{}).
+		Select("events.*, rsvps.status AS rsvp_status").
+		Joins("JOIN rsvps ON rsvps.event_id = events.id").
+		Where("rsvps.user_id = ? AND rsvps.status <> ?", user.ID, models.RSVPCancelled).
+		Where("events.status <> ? AND events.start_time IS NOT NULL AND events.start_time >= ?", models.EventDraft, time.Now().Add(-calendarFeedLookback)).
+		Order("events.start_time asc").
+		Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	entries := make([]utils.CalendarEvent, 0, len(rows))
+	for _, row := range rows {
+		// A waitlisted spot isn't confirmed yet
+		status := "CONFIRMED"
+		if row.RSVPStatus == string(models.RSVPWaitlisted) {
+			status = "TENTATIVE"
+		}
+		entries = append(entries, calendarEntry(row.Event, status))
+	}
+	writeCalendar(c, "My EcoCampus events", "my-events.ics", entries)
+}
+
+// GetEventICS downloads a single event as an .ics file
+func GetEventICS(c *gin.Context) {
+	eventID := c.Param("eventId")
+
+	var event models.Event
+	if err := database.DB.First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if event.Status == string(models.EventDraft) && !canManageEvent(c, event) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if event.StartTime == nil || event.EndTime == nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Event has no scheduled time"})
+		return
+	}
+
+	writeCalendar(c, event.Name, fmt.Sprintf("event-%d.ics", event.ID), []utils.CalendarEvent{calendarEntry(event, "CONFIRMED")})
+}
+
+// Helper function to resolve the calendar token of a feed URL, responds with 404 when it is unknown
+func userByCalendarToken(c *gin.Context) (models.User, bool) {
+	var user models.User
+	token := c.Param("token")
+	if token == "" || database.DB.Where("calendar_token = ?", token).First(&user).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// Helper function to convert an event into a VEVENT, cancelled events override the given status
+func calendarEntry(event models.Event, status string) utils.CalendarEvent {
+	if event.Status == string(models.EventCancelled) {
+		status = "CANCELLED"
+	}
+
+	entry := utils.CalendarEvent{
+		UID:          fmt.Sprintf("event-%d@%s", event.ID, calendarUIDDomain()),
+		Sequence:     event.Sequence,
+		Summary:      event.Name,
+		Description:  event.Description,
+		Location:     event.Location,
+		Status:       status,
+		LastModified: event.UpdatedAt,
+	}
+	if event.StartTime != nil {
+		entry.Start = *event.StartTime
+	}
+	if event.EndTime != nil {
+		entry.End = *event.EndTime
+	}
+	return entry
+}
+
+// Helper function to send an iCalendar document
+func writeCalendar(c *gin.Context, name, filename string, entries []utils.CalendarEvent) {
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(utils.BuildCalendar(name, entries)))
+}
+
+// Helper function to issue a new calendar token for a user
+func rotateCalendarToken(user *models.User) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+	user.CalendarToken = token
+	return database.DB.Model(user).Update("calendar_token", token).Error
+}
+
+// Helper function to build the feed URLs of a calendar token
+func calendarFeedURLs(token string) gin.H {
+	return gin.H{
+		"events_feed":   fmt.Sprintf("/calendar/%s/events.ics", token),
+		"personal_feed": fmt.Sprintf("/calendar/%s/my-events.ics", token),
+	}
+}
+
+// Helper function to get the domain used in event UIDs, it must never change once feeds are subscribed
+func calendarUIDDomain() string {
+	if domain := os.Getenv("CALENDAR_UID_DOMAIN"); domain != "" {
+		return domain
+	}
+	return "ecocampus-passport"
+}
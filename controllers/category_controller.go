@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// categoryWithCounts is a category along with how much it is used
+type categoryWithCounts struct {
+	models.Category
+	EventCount      int64 `json:"event_count"`
+	AttendanceCount int64 `json:"attendance_count"`
+}
+
+// tagWithCount is a tag along with the number of events using it
+type tagWithCount struct {
+	models.Tag
+	EventCount int64 `json:"event_count"`
+}
+
+// GetCategories lists every category with the number of events and attendances in it
+func GetCategories(c *gin.Context) {
+	// Drafts are not real events yet and deleted ones are gone, so they are left out of the counts
+	query := database.DB.Table("categories").
+		Select("categories.*, COUNT(DISTINCT events.id) AS event_count, COUNT(attendances.id) AS attendance_count").
+		Joins("LEFT JOIN events ON events.category_id = categories.id AND events.status <> ? AND events.deleted_at IS NULL", models.EventDraft).
+		Joins("LEFT JOIN attendances ON attendances.event_id = events.id").
+		Group("categories.id")
+
+	page, err := pagination.Paginate(c, query, pagination.Key[categoryWithCounts]{
+		Column:   "categories.name",
+		Kind:     pagination.KindString,
+		IDColumn: "categories.id",
+		Value:    func(cat categoryWithCounts) interface{} { return cat.Name },
+		ID:       func(cat categoryWithCounts) uint { return cat.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// CreateCategory adds a category to the taxonomy (requires admin permission)
+func CreateCategory(c *gin.Context) {
+	var input struct {
+		Name        string `json:"name" binding:"required"`
+		Slug        string `json:"slug"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Slug == "" {
+		input.Slug = input.Name
+	}
+	category := models.Category{
+		Name:        strings.TrimSpace(input.Name),
+		Slug:        slugify(input.Slug),
+		Description: input.Description,
+	}
+	if category.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must contain letters or digits"})
+		return
+	}
+
+	if err := database.DB.Create(&category).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this name or slug already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory renames or re-describes a category (requires admin permission)
+func UpdateCategory(c *gin.Context) {
+	categoryID := c.Param("categoryId")
+	var category models.Category
+	if err := database.DB.First(&category, categoryID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Slug        *string `json:"slug"`
+		Description *string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Name != nil {
+		category.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Slug != nil {
+		category.Slug = slugify(*input.Slug)
+	}
+	if input.Description != nil {
+		category.Description = *input.Description
+	}
+	if category.Name == "" || category.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and slug must not be empty"})
+		return
+	}
+
+	if err := database.DB.Save(&category).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this name or slug already exists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory removes a category, its events and templates become uncategorized (requires admin permission)
+func DeleteCategory(c *gin.Context) {
+	categoryID := c.Param("categoryId")
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Event{}).Where("category_id = ?", categoryID).
+			Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.EventTemplate{}).Where("category_id = ?", categoryID).
+			Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Category{}, categoryID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
+}
+
+// GetTags lists every tag with the number of events using it
+func GetTags(c *gin.Context) {
+	query := database.DB.Table("tags").
+		Select("tags.*, COUNT(events.id) AS event_count").
+		Joins("LEFT JOIN event_tags ON event_tags.tag_id = tags.id").
+		Joins("LEFT JOIN events ON events.id = event_tags.event_id AND events.status <> ? AND events.deleted_at IS NULL", models.EventDraft).
+		Group("tags.id")
+
+	page, err := pagination.Paginate(c, query, pagination.Key[tagWithCount]{
+		Column:   "tags.name",
+		Kind:     pagination.KindString,
+		IDColumn: "tags.id",
+		Value:    func(t tagWithCount) interface{} { return t.Name },
+		ID:       func(t tagWithCount) uint { return t.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// Helper function to find tags by name, creating the ones that don't exist yet
+func findOrCreateTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	seen := make(map[string]bool)
+	var tags []models.Tag
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, models.Tag{Name: name})
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	// Tags that already existed don't get their IDs back from the insert
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalized = append(normalized, tag.Name)
+	}
+	var found []models.Tag
+	if err := tx.Where("name IN ?", normalized).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// Helper function to turn a category name into a URL friendly slug
+func slugify(value string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(value), "-"), "-")
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
+	"gorm.io/gorm"
+)
+
+// GetCompetitions lists competitions, most recent first. status=upcoming, active or ended narrows them down.
+func GetCompetitions(c *gin.Context) {
+	query := database.DB.Model(&models.Competition{}).Preload("Teams").Preload("WinnerTeam")
+	now := time.Now()
+	switch c.Query("status") {
+	case "":
+	case "upcoming":
+		query = query.Where("starts_at > ?", now)
+	case "active":
+		query = query.Where("starts_at <= ? AND ends_at > ?", now, now)
+	case "ended":
+		query = query.Where("ends_at <= ?", now)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be upcoming, active or ended"})
+		return
+	}
+
+	page, err := pagination.Paginate(c, query, pagination.Key[models.Competition]{
+		Column:   "starts_at",
+		Kind:     pagination.KindTime,
+		IDColumn: "id",
+		Desc:     true,
+		Value:    func(comp models.Competition) interface{} { return comp.StartsAt },
+		ID:       func(comp models.Competition) uint { return comp.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve competitions")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetCompetition retrieves a single competition along with its live (or final) standings
+func GetCompetition(c *gin.Context) {
+	var competition models.Competition
+	if err := database.DB.Preload("Teams").Preload("WinnerTeam").First(&competition, c.Param("competitionId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Competition not found"})
+		return
+	}
+
+	standings, err := services.CompetitionScores(database.DB, competition)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute competition standings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"competition": competition,
+		"standings":   standings,
+	})
+}
+
+// CreateCompetition sets up a competition between at least two teams (requires admin permission)
+func CreateCompetition(c *gin.Context) {
+	var input struct {
+		Name        string    `json:"name" binding:"required"`
+		Description string    `json:"description"`
+		StartsAt    time.Time `json:"starts_at" binding:"required"`
+		EndsAt      time.Time `json:"ends_at" binding:"required"`
+		TeamIDs     []uint    `json:"team_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	competition := models.Competition{
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+		StartsAt:    input.StartsAt,
+		EndsAt:      input.EndsAt,
+	}
+	if msg := validateCompetition(competition); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	teams, msg := competitionTeams(database.DB, input.TeamIDs)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	competition.Teams = teams
+
+	if err := database.DB.Create(&competition).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create competition"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, competition)
+}
+
+// UpdateCompetition partially updates a competition whose winner wasn't declared yet, team_ids replaces the
+// teams taking part (requires admin permission)
+func UpdateCompetition(c *gin.Context) {
+	var input struct {
+		Name        *string    `json:"name"`
+		Description *string    `json:"description"`
+		StartsAt    *time.Time `json:"starts_at"`
+		EndsAt      *time.Time `json:"ends_at"`
+		TeamIDs     *[]uint    `json:"team_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var competition models.Competition
+	if err := database.DB.First(&competition, c.Param("competitionId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Competition not found"})
+		return
+	}
+	if competition.DeclaredAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The winner was already declared, the competition can't change"})
+		return
+	}
+
+	if input.Name != nil {
+		competition.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		competition.Description = *input.Description
+	}
+	if input.StartsAt != nil {
+		competition.StartsAt = *input.StartsAt
+	}
+	if input.EndsAt != nil {
+		competition.EndsAt = *input.EndsAt
+	}
+	if msg := validateCompetition(competition); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	var teams []models.Team
+	if input.TeamIDs != nil {
+		var msg string
+		if teams, msg = competitionTeams(database.DB, *input.TeamIDs); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Teams", "WinnerTeam").Save(&competition).Error; err != nil {
+			return err
+		}
+		if input.TeamIDs != nil {
+			return tx.Model(&competition).Association("Teams").Replace(teams)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update competition"})
+		return
+	}
+
+	database.DB.Preload("Teams").First(&competition, competition.ID)
+	c.JSON(http.StatusOK, competition)
+}
+
+// DeleteCompetition removes a competition (requires admin permission)
+func DeleteCompetition(c *gin.Context) {
+	var competition models.Competition
+	if err := database.DB.First(&competition, c.Param("competitionId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Competition not found"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&competition).Association("Teams").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&competition).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete competition"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Competition deleted"})
+}
+
+// DeclareCompetitionWinner declares the winner of a competition that has ended, the top team unless team_id
+// names another one. Ties have to be settled by naming the winner. (requires admin permission)
+func DeclareCompetitionWinner(c *gin.Context) {
+	competitionID, err := strconv.Atoi(c.Param("competitionId"))
+	if err != nil || competitionID <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Competition not found"})
+		return
+	}
+	var input struct {
+		TeamID uint `json:"team_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	competition, err := services.DeclareWinner(database.DB, uint(competitionID), input.TeamID, c.GetUint("user_id"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Competition not found"})
+		return
+	case errors.Is(err, services.ErrCompetitionDeclared):
+		c.JSON(http.StatusConflict, gin.H{"error": "The winner was already declared"})
+		return
+	case errors.Is(err, services.ErrCompetitionNotEnded):
+		c.JSON(http.StatusConflict, gin.H{"error": "The winner can only be declared once the competition has ended"})
+		return
+	case errors.Is(err, services.ErrCompetitionTied):
+		c.JSON(http.StatusConflict, gin.H{"error": "The competition is tied, pass team_id to settle it"})
+		return
+	case errors.Is(err, services.ErrCompetitionNoTeam):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The team does not take part in the competition"})
+		return
+	case errors.Is(err, services.ErrCompetitionNoEntries):
+		c.JSON(http.StatusConflict, gin.H{"error": "No teams take part in the competition"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to declare winner"})
+		return
+	}
+
+	database.DB.Preload("Teams").Preload("WinnerTeam").First(&competition, competition.ID)
+	c.JSON(http.StatusOK, competition)
+}
+
+// Helper function to validate a competition, returns an error message or ""
+func validateCompetition(competition models.Competition) string {
+	if competition.Name == "" {
+		return "name must not be empty"
+	}
+	if !competition.StartsAt.Before(competition.EndsAt) {
+		return "starts_at must be before ends_at"
+	}
+	return ""
+}
+
+// Helper function to load the teams of a competition, returns an error message or "". Teams must all be of
+// the same kind so houses don't compete against clubs.
+func competitionTeams(db *gorm.DB, teamIDs []uint) ([]models.Team, string) {
+	var teams []models.Team
+	if err := db.Where("id IN ?", teamIDs).Find(&teams).Error; err != nil {
+		return nil, "Failed to load teams"
+	}
+	if len(teams) < 2 {
+		return nil, "team_ids must name at least two existing teams"
+	}
+	requested := make(map[uint]bool, len(teamIDs))
+	for _, id := range teamIDs {
+		requested[id] = true
+	}
+	if len(teams) != len(requested) {
+		return nil, "team_ids contains unknown teams"
+	}
+	for _, team := range teams {
+		if team.Kind != teams[0].Kind {
+			return nil, "Teams of a competition must all be of the same kind"
+		}
+	}
+	return teams, ""
+}
@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -10,18 +13,27 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/open-cmuq/passport-backend/database"
 	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GetEvents retrieves a list of all events with optional filters
 func GetEvents(c *gin.Context) {
 	// Parse and validate query parameters
 	queryParams := struct {
-		BeforeTime  string `form:"before_time"`
-		AfterTime   string `form:"after_time"`
-		BetweenTime string `form:"between_time"` // comma-separated start,end
-		Limit       string `form:"limit"`
-		Order       string `form:"order"` // "asc" or "desc"
+		BeforeTime  string   `form:"before_time"`
+		AfterTime   string   `form:"after_time"`
+		BetweenTime string   `form:"between_time"` // comma-separated start,end
+		Order       string   `form:"order"`        // "asc" or "desc"
+		Status      []string `form:"status"`       // repeatable or comma-separated, defaults to published and cancelled
+		Category    []string `form:"category"`     // category slugs or IDs, repeatable or comma-separated
+		Tag         []string `form:"tag"`          // tag names, repeatable or comma-separated
+		Venue       []string `form:"venue"`        // venue IDs, repeatable or comma-separated
+		Mode        []string `form:"mode"`         // "in_person", "online" or "hybrid", repeatable or comma-separated
+		Q           string   `form:"q"`            // full-text search over name, description and location
 	}{
 		Order: "desc", // default to newest first
 	}
@@ -38,7 +50,76 @@ func GetEvents(c *gin.Context) {
 	}
 
 	// Build the base query
-	query := database.DB.Preload("Organizer").Preload("Awards")
+	query := database.DB.Model(&models.Event{})
+
+	// Filter by status, drafts and archived events are left out unless explicitly requested
+	statuses := splitQueryValues(queryParams.Status)
+	if len(statuses) == 0 {
+		statuses = []string{string(models.EventPublished), string(models.EventCancelled)}
+	}
+	for _, status := range statuses {
+		if !isEventStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of 'draft', 'published', 'cancelled' or 'archived'"})
+			return
+		}
+	}
+	query = query.Where("status IN ?", statuses)
+
+	// Drafts are only visible to their organizer, staff and admins
+	if !isStaff(c) {
+		query = query.Where("(status <> ? OR organizer_id = ?)", models.EventDraft, c.GetUint("user_id"))
+	}
+
+	// Filter by category, matching any of the given slugs or IDs
+	if categories := splitQueryValues(queryParams.Category); len(categories) > 0 {
+		var categoryIDs []uint
+		var slugs []string
+		for _, category := range categories {
+			if id, err := strconv.Atoi(category); err == nil {
+				categoryIDs = append(categoryIDs, uint(id))
+			} else {
+				slugs = append(slugs, category)
+			}
+		}
+		query = query.Where("category_id IN (?)",
+			database.DB.Model(&models.Category{}).Select("id").Where("id IN ? OR slug IN ?", categoryIDs, slugs))
+	}
+
+	// Filter by tag, matching events that have any of the given tags
+	if tags := splitQueryValues(queryParams.Tag); len(tags) > 0 {
+		for i := range tags {
+			tags[i] = strings.ToLower(tags[i])
+		}
+		query = query.Where("id IN (?)",
+			database.DB.Table("event_tags").Select("event_tags.event_id").
+				Joins("JOIN tags ON tags.id = event_tags.tag_id").
+				Where("tags.name IN ?", tags))
+	}
+
+	// Filter by venue, matching any of the given IDs
+	if venues := splitQueryValues(queryParams.Venue); len(venues) > 0 {
+		venueIDs := make([]uint, 0, len(venues))
+		for _, venue := range venues {
+			id, err := strconv.Atoi(venue)
+			if err != nil || id <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "venue must be a list of venue IDs"})
+				return
+			}
+			venueIDs = append(venueIDs, uint(id))
+		}
+		query = query.Where("venue_id IN ?", venueIDs)
+	}
+
+	// Filter by mode, matching any of the given modes
+	if modes := splitQueryValues(queryParams.Mode); len(modes) > 0 {
+		for _, mode := range modes {
+			if !isEventMode(mode) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be one of 'in_person', 'online' or 'hybrid'"})
+				return
+			}
+		}
+		query = query.Where("mode IN ?", modes)
+	}
 
 	// Parse and validate time filters
 	var beforeTime, afterTime time.Time
@@ -92,27 +173,49 @@ func GetEvents(c *gin.Context) {
 		query = query.Where("start_time BETWEEN ? AND ?", startTime, endTime)
 	}
 
-	// Apply ordering
-	query = query.Order(fmt.Sprintf("start_time %s", queryParams.Order))
+	// Handle full-text search, ranking matches and highlighting the matched terms
+	search := strings.TrimSpace(queryParams.Q)
+	if search != "" {
+		tsQuery := "websearch_to_tsquery('english', ?)"
+		headline := "ts_headline('english', coalesce(%s, ''), " + tsQuery + ", 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')"
+		query = query.
+			Select("events.*, ts_rank("+models.EventSearchVector+", "+tsQuery+") AS search_rank, "+
+				fmt.Sprintf(headline, "name")+" AS name_highlight, "+
+				fmt.Sprintf(headline, "description")+" AS description_highlight",
+				search, search, search).
+			Where(models.EventSearchVector+" @@ "+tsQuery, search)
+	}
 
-	// Apply limit if specified
-	if queryParams.Limit != "" {
-		limit, err := strconv.Atoi(queryParams.Limit)
-		if err != nil || limit <= 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
-			return
-		}
-		query = query.Limit(limit)
+	// Order by start time, or by relevance when searching unless an order was requested
+	key := pagination.Key[models.Event]{
+		Column:   "COALESCE(events.start_time, 'epoch'::timestamptz)", // events without a time sort as the oldest
+		Kind:     pagination.KindTime,
+		IDColumn: "events.id",
+		Desc:     queryParams.Order == "desc",
+		Value: func(e models.Event) interface{} {
+			if e.StartTime == nil {
+				return time.Unix(0, 0).UTC()
+			}
+			return *e.StartTime
+		},
+		ID: func(e models.Event) uint { return e.ID },
+	}
+	if search != "" && c.Query("order") == "" {
+		key.Column = "ts_rank(" + models.EventSearchVector + ", websearch_to_tsquery('english', ?))"
+		key.Vars = []interface{}{search}
+		key.Kind = pagination.KindFloat
+		key.Desc = true
+		key.Value = func(e models.Event) interface{} { return *e.SearchRank }
 	}
 
-	// Fetch events
-	var events []models.Event
-	if err := query.Find(&events).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+	// Fetch a page of events, or all of them for clients that don't page
+	page, err := pagination.PaginateIfRequested(c, query, key, preloadEventRelations)
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve events")
 		return
 	}
 
-	c.JSON(http.StatusOK, events)
+	c.JSON(http.StatusOK, page)
 }
 
 // CreateEvent creates a new event (requires admin permission)
@@ -126,12 +229,84 @@ func CreateEvent(c *gin.Context) {
 		PointsAllocation int        `json:"points_allocation"`
 		AwardIDs         *[]uint    `json:"award_ids"`
 		ImageURL         string     `json:"image_url"`
+		Capacity         *int       `json:"capacity"`
+		RSVPDeadline     *time.Time `json:"rsvp_deadline"`
+		Status           string     `json:"status"` // "published" (default) or "draft"
+		CategoryID       *uint      `json:"category_id"`
+		Tags             []string   `json:"tags"`
+		TemplateID       *uint      `json:"template_id"` // Fills in the fields left empty from an event template
+		VenueID          *uint      `json:"venue_id"`    // Sets the location (and capacity) when they are left empty
+		Mode             string     `json:"mode"`        // "in_person" (default), "online" or "hybrid"
+		JoinURL          string     `json:"join_url"`    // Stream link of online and hybrid events
+		MeetingID        string     `json:"meeting_id"`  // Meeting on the streaming platform, for webhook attendance
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Events are published right away unless they are created as drafts, as before drafts existed
+	if input.Status == "" {
+		input.Status = string(models.EventPublished)
+	}
+	if input.Status != string(models.EventDraft) && input.Status != string(models.EventPublished) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be either 'draft' or 'published'"})
+		return
+	}
+
+	// Start from the template, the fields sent in the request take precedence
+	if input.TemplateID != nil {
+		var template models.EventTemplate
+		if err := database.DB.Preload("Awards").First(&template, *input.TemplateID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+			return
+		}
+		if input.Description == "" {
+			input.Description = template.Description
+		}
+		if input.Location == "" {
+			input.Location = template.Location
+		}
+		if input.PointsAllocation == 0 {
+			input.PointsAllocation = template.PointsAllocation
+		}
+		if input.ImageURL == "" {
+			input.ImageURL = template.ImageURL
+		}
+		if input.CategoryID == nil {
+			input.CategoryID = template.CategoryID
+		}
+		if input.Capacity == nil {
+			input.Capacity = template.Capacity
+		}
+		if input.AwardIDs == nil && len(template.Awards) > 0 {
+			awardIDs := make([]uint, 0, len(template.Awards))
+			for _, award := range template.Awards {
+				awardIDs = append(awardIDs, award.ID)
+			}
+			input.AwardIDs = &awardIDs
+		}
+	}
+
+	// Fill in the location and capacity from the venue
+	if input.VenueID != nil {
+		var venue models.Venue
+		if err := database.DB.First(&venue, *input.VenueID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid venue ID"})
+			return
+		}
+		if input.Location == "" {
+			input.Location = venue.Name
+		}
+		if input.Capacity == nil {
+			input.Capacity = venue.Capacity
+		}
+		if msg := validateVenueCapacity(venue, input.Capacity); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
 	// Validate start and end times
 	if (input.StartTime == nil && input.EndTime != nil) || (input.StartTime != nil && input.EndTime == nil) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time and end_time must both be nil or both have values"})
@@ -143,6 +318,17 @@ func CreateEvent(c *gin.Context) {
 			return
 		}
 	}
+	if msg := validateRSVPSettings(input.Capacity, input.RSVPDeadline, input.EndTime); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if input.Mode == "" {
+		input.Mode = string(models.EventInPerson)
+	}
+	if msg := validateEventMode(input.Mode, input.JoinURL); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// Get the organizer ID from the JWT token
 	organizerID := c.GetUint("user_id")
@@ -156,6 +342,21 @@ func CreateEvent(c *gin.Context) {
 		}
 	}
 
+	// Check the category exists
+	if input.CategoryID != nil {
+		if err := database.DB.First(&models.Category{}, *input.CategoryID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+			return
+		}
+	}
+
+	// Resolve the tags, creating new ones as needed
+	tags, err := findOrCreateTags(database.DB, input.Tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tags"})
+		return
+	}
+
 	// Create the event
 	event := models.Event{
 		Name:             input.Name,
@@ -166,7 +367,16 @@ func CreateEvent(c *gin.Context) {
 		OrganizerID:      organizerID,
 		PointsAllocation: input.PointsAllocation,
 		ImageURL:         input.ImageURL,
+		Status:           input.Status,
+		CategoryID:       input.CategoryID,
+		VenueID:          input.VenueID,
+		Mode:             input.Mode,
+		JoinURL:          strings.TrimSpace(input.JoinURL),
+		MeetingID:        strings.TrimSpace(input.MeetingID),
+		Capacity:         input.Capacity,
+		RSVPDeadline:     input.RSVPDeadline,
 		Awards:           awards,
+		Tags:             tags,
 	}
 	if err := database.DB.Create(&event).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
@@ -176,6 +386,105 @@ func CreateEvent(c *gin.Context) {
 	c.JSON(http.StatusCreated, event)
 }
 
+// CloneEvent copies an event, including its awards, category and tags, into a new draft shifted in time
+func CloneEvent(c *gin.Context) {
+	eventID := c.Param("eventId")
+	var input struct {
+		Name      *string    `json:"name"`       // Defaults to the name of the original event
+		StartTime *time.Time `json:"start_time"` // New start time, the other times move along with it
+		ShiftDays *int       `json:"shift_days"` // Alternatively, number of days to move every time by
+		Status    string     `json:"status"`     // "draft" (default) or "published"
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.StartTime != nil && input.ShiftDays != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time and shift_days can't be used together"})
+		return
+	}
+	if input.Status == "" {
+		input.Status = string(models.EventDraft)
+	}
+	if input.Status != string(models.EventDraft) && input.Status != string(models.EventPublished) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be either 'draft' or 'published'"})
+		return
+	}
+
+	var source models.Event
+	if err := database.DB.Preload("Awards").Preload("Tags").First(&source, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if source.Status == string(models.EventDraft) && !canManageEvent(c, source) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if input.StartTime != nil && source.StartTime == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The event has no start_time to move, use shift_days or update the clone instead"})
+		return
+	}
+
+	// Move every time of the event by the same amount, days are added on the calendar so the
+	// wall-clock time survives daylight saving changes
+	shift := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		var shifted time.Time
+		switch {
+		case input.StartTime != nil:
+			shifted = t.Add(input.StartTime.Sub(*source.StartTime))
+		case input.ShiftDays != nil:
+			shifted = t.AddDate(0, 0, *input.ShiftDays)
+		default:
+			shifted = *t
+		}
+		return &shifted
+	}
+
+	clone := models.Event{
+		Name:             source.Name,
+		Description:      source.Description,
+		Location:         source.Location,
+		VenueID:          source.VenueID,
+		Mode:             source.Mode,
+		JoinURL:          source.JoinURL,
+		MeetingID:        source.MeetingID,
+		StartTime:        shift(source.StartTime),
+		EndTime:          shift(source.EndTime),
+		OrganizerID:      c.GetUint("user_id"),
+		PointsAllocation: source.PointsAllocation,
+		ImageURL:         source.ImageURL,
+		ThumbnailURL:     source.ThumbnailURL,
+		Status:           input.Status,
+		CategoryID:       source.CategoryID,
+		Capacity:         source.Capacity,
+		RSVPDeadline:     shift(source.RSVPDeadline),
+		Awards:           source.Awards,
+		Tags:             source.Tags,
+	}
+	if input.Name != nil {
+		clone.Name = strings.TrimSpace(*input.Name)
+	}
+	if clone.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+		return
+	}
+
+	if err := database.DB.Omit("Awards.*", "Tags.*").Create(&clone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone event"})
+		return
+	}
+	if err := database.DB.Scopes(preloadEventRelations).First(&clone, clone.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload event"})
+		return
+	}
+
+	c.Header("ETag", eventETag(clone))
+	c.JSON(http.StatusCreated, clone)
+}
+
 // GetEvent retrieves details of a specific event
 // TODO Consider the case where we have 1000s of events, this would lead to blocking the API and slow performance.
 // however, this is sufficient for our current needs
@@ -184,44 +493,60 @@ func GetEvent(c *gin.Context) {
 	var event models.Event
 
 	// Fetch the event with all relationships
-	if err := database.DB.Preload("Organizer").Preload("Awards").Preload("Attendees").First(&event, eventID).Error; err != nil {
+	if err := database.DB.Scopes(preloadEventRelations).First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	// Drafts are only visible to the people who can manage them
+	if event.Status == string(models.EventDraft) && !canManageEvent(c, event) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
 	// TODO Consider sharing the event information as the user gets the event
+	c.Header("ETag", eventETag(event))
 	c.JSON(http.StatusOK, event)
 }
 
-// UpdateEvent updates event details (requires admin permission)
+// UpdateEvent partially updates event details, fields missing from the body are left unchanged (requires admin permission)
+// The ETag from GetEvent must be sent as If-Match so nobody overwrites changes they haven't seen
 func UpdateEvent(c *gin.Context) {
 	eventID := c.Param("eventId")
 	var input struct {
-		Name             string     `json:"name"`
-		Description      string     `json:"description"`
-		Location         string     `json:"location"`
-		StartTime        *time.Time `json:"start_time"`
-		EndTime          *time.Time `json:"end_time"`
-		PointsAllocation int        `json:"points_allocation"`
-		AwardIDs         []uint     `json:"award_ids"`
-		ImageURL         string     `json:"image_url"`
+		Name             *string                   `json:"name"`
+		Description      *string                   `json:"description"`
+		Location         *string                   `json:"location"`
+		StartTime        utils.Nullable[time.Time] `json:"start_time"` // null removes the schedule (together with end_time)
+		EndTime          utils.Nullable[time.Time] `json:"end_time"`
+		PointsAllocation *int                      `json:"points_allocation"`
+		AwardIDs         []uint                    `json:"award_ids"`    // Replaces the awards when non-empty
+		ClearAwards      bool                      `json:"clear_awards"` // Removes every award, an empty award_ids list doesn't
+		ImageURL         *string                   `json:"image_url"`
+		Capacity         utils.Nullable[int]       `json:"capacity"`      // null means unlimited
+		RSVPDeadline     utils.Nullable[time.Time] `json:"rsvp_deadline"` // null removes the deadline
+		CategoryID       utils.Nullable[uint]      `json:"category_id"`   // null makes the event uncategorized
+		VenueID          utils.Nullable[uint]      `json:"venue_id"`      // null unlinks the venue, the location is kept
+		Tags             *[]string                 `json:"tags"`          // Replaces the tags when present, an empty list clears them
+		Mode             *string                   `json:"mode"`
+		JoinURL          *string                   `json:"join_url"` // An empty string removes the link
+		MeetingID        *string                   `json:"meeting_id"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Validate start and end times
-	if (input.StartTime == nil && input.EndTime != nil) || (input.StartTime != nil && input.EndTime == nil) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time and end_time must both be nil or both have values"})
+	if input.ClearAwards && len(input.AwardIDs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "clear_awards can't be combined with award_ids"})
 		return
 	}
-	if input.StartTime != nil && input.EndTime != nil {
-		if input.StartTime.After(*input.EndTime) || input.StartTime.Equal(*input.EndTime) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "start_time must be before end_time"})
-			return
-		}
+	if input.Name != nil && strings.TrimSpace(*input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+		return
+	}
+	if !requireIfMatch(c) {
+		return
 	}
 
 	// Start a transaction
@@ -239,14 +564,59 @@ func UpdateEvent(c *gin.Context) {
 		}
 	}()
 
-	// Fetch the event within transaction
+	// Fetch and lock the event within transaction so capacity changes don't race with RSVPs
 	var event models.Event
-	if err := tx.First(&event, eventID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
+	// The row is locked, so the version can't change between this check and the save
+	if !checkEventEditable(c, event) {
+		tx.Rollback()
+		return
+	}
+
+	// Merge the changes so the validation sees the event as it will be saved
+	startTime := input.StartTime.Or(event.StartTime)
+	endTime := input.EndTime.Or(event.EndTime)
+	capacity := input.Capacity.Or(event.Capacity)
+	rsvpDeadline := input.RSVPDeadline.Or(event.RSVPDeadline)
+	categoryID := input.CategoryID.Or(event.CategoryID)
+	venueID := input.VenueID.Or(event.VenueID)
+	mode, joinURL := event.Mode, event.JoinURL
+	if input.Mode != nil {
+		mode = *input.Mode
+	}
+	if input.JoinURL != nil {
+		joinURL = strings.TrimSpace(*input.JoinURL)
+	}
+
+	// Validate start and end times
+	if (startTime == nil && endTime != nil) || (startTime != nil && endTime == nil) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time and end_time must both be nil or both have values"})
+		return
+	}
+	if startTime != nil && endTime != nil {
+		if startTime.After(*endTime) || startTime.Equal(*endTime) {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_time must be before end_time"})
+			return
+		}
+	}
+	if msg := validateRSVPSettings(capacity, rsvpDeadline, endTime); msg != "" {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if msg := validateEventMode(mode, joinURL); msg != "" {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	// Fetch the awards within transaction if award IDs are provided
 	var awards []models.Award
 	if len(input.AwardIDs) > 0 {
@@ -263,113 +633,426 @@ func UpdateEvent(c *gin.Context) {
 		}
 	}
 
-	// Update the event
-	event.Name = input.Name
-	event.Description = input.Description
-	event.Location = input.Location
-	event.StartTime = input.StartTime // nil or value both handled
-	event.EndTime = input.EndTime     // nil or value both handled
-	event.PointsAllocation = input.PointsAllocation
-	event.ImageURL = input.ImageURL
+	// Check the category exists
+	if input.CategoryID.Value != nil {
+		if err := tx.First(&models.Category{}, *input.CategoryID.Value).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+			return
+		}
+	}
 
-	// Only update awards if new ones were provided
-	if len(input.AwardIDs) > 0 {
-		event.Awards = awards
+	// Check the venue exists and can hold the event, a new venue also becomes the location
+	if venueID != nil {
+		var venue models.Venue
+		if err := tx.First(&venue, *venueID).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid venue ID"})
+			return
+		}
+		// Only checked when one of them changes so events linked to a venue later stay editable
+		if input.VenueID.Set || input.Capacity.Set {
+			if msg := validateVenueCapacity(venue, capacity); msg != "" {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+		}
+		if input.VenueID.Set && input.Location == nil {
+			event.Location = venue.Name
+		}
+	}
+
+	// Apply only the fields that were sent
+	previousPoints := event.PointsAllocation
+	previousCategoryID := event.CategoryID
+	if input.Name != nil {
+		event.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		event.Description = *input.Description
+	}
+	if input.Location != nil {
+		event.Location = *input.Location
+	}
+	if input.PointsAllocation != nil {
+		event.PointsAllocation = *input.PointsAllocation
 	}
+	if input.ImageURL != nil && *input.ImageURL != event.ImageURL {
+		event.ImageURL = *input.ImageURL
+		event.ThumbnailURL = "" // The thumbnail belonged to the previous image
+	}
+	event.StartTime = startTime
+	event.EndTime = endTime
+	event.Capacity = capacity
+	event.RSVPDeadline = rsvpDeadline
+	event.CategoryID = categoryID
+	event.VenueID = venueID
+	event.Mode = mode
+	event.JoinURL = joinURL
+	if input.MeetingID != nil {
+		event.MeetingID = strings.TrimSpace(*input.MeetingID)
+	}
+	event.Sequence++
 
-	// Save within transaction
-	if err := tx.Save(&event).Error; err != nil {
+	// Save within transaction, associations are handled explicitly below
+	if err := tx.Omit(clause.Associations).Save(&event).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
 		return
 	}
 
+	// Attendees were credited with the previous points, adjust their balances by the difference
+	if _, err := services.RecalculateEventPoints(tx, event, previousPoints, c.GetUint("user_id")); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recalculate attendee points"})
+		return
+	}
+
+	// Award criteria can count events by category
+	if !sameID(previousCategoryID, event.CategoryID) {
+		if _, err := services.EvaluateEventAttendees(tx, event); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to re-evaluate attendee awards"})
+			return
+		}
+	}
+
+	// Replace the awards if new ones were provided, they are only cleared when explicitly asked to
+	if len(input.AwardIDs) > 0 {
+		if err := tx.Model(&event).Association("Awards").Replace(awards); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update awards"})
+			return
+		}
+	} else if input.ClearAwards {
+		if err := tx.Model(&event).Association("Awards").Clear(); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear awards"})
+			return
+		}
+	}
+	// Attendees hold the event's awards, so they gain the new ones and lose the removed ones
+	if len(input.AwardIDs) > 0 || input.ClearAwards {
+		if _, err := services.EvaluateEventAttendees(tx, event); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to re-evaluate attendee awards"})
+			return
+		}
+	}
+
+	// Replace the tags if new ones were provided
+	if input.Tags != nil {
+		tags, err := findOrCreateTags(tx, *input.Tags)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tags"})
+			return
+		}
+		if err := tx.Model(&event).Association("Tags").Replace(tags); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+			return
+		}
+	}
+
+	// A raised (or removed) capacity may free spots for waitlisted users
+	promoted, err := promoteWaitlist(tx, event)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote waitlist"})
+		return
+	}
+
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
+	notifyPromoted(event, promoted)
+
+	// Respond with the full event as stored
+	if err := database.DB.Scopes(preloadEventRelations).First(&event, event.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload event"})
+		return
+	}
+	c.Header("ETag", eventETag(event))
+	c.JSON(http.StatusOK, event)
+}
+
+// UpdateEventStatus moves an event through its lifecycle (draft, published, cancelled, archived)
+func UpdateEventStatus(c *gin.Context) {
+	eventID := c.Param("eventId")
+	var input struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"` // Shown to RSVP'd users when cancelling
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !isEventStatus(input.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of 'draft', 'published', 'cancelled' or 'archived'"})
+		return
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	var event models.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if !canManageEvent(c, event) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to update this event"})
+		return
+	}
+	if !ifMatchesEvent(c, event) {
+		tx.Rollback()
+		c.Header("ETag", eventETag(event))
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "The event was modified by someone else, reload it and try again"})
+		return
+	}
+	if !event.CanTransitionTo(models.EventStatus(input.Status)) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("An event can't move from %s to %s", event.Status, input.Status)})
+		return
+	}
+
+	event.Status = input.Status
+	event.Sequence++
+	if input.Status == string(models.EventCancelled) {
+		event.CancellationReason = input.Reason
+	}
+	if err := tx.Omit(clause.Associations).Save(&event).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event status"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	if event.Status == string(models.EventCancelled) {
+		notifyEventCancelled(event)
+	}
+
+	c.Header("ETag", eventETag(event))
 	c.JSON(http.StatusOK, event)
 }
 
-// DeleteEvent deletes an event (requires admin permission)
+// DeleteEvent soft-deletes an event and takes its points back from attendees, it can be restored
+// until it is purged (requires admin permission)
 func DeleteEvent(c *gin.Context) {
 	eventID := c.Param("eventId")
 
 	tx := database.DB.Begin()
 
-	// 1. Get event points allocation
+	// 1. Get and lock the event
 	var event models.Event
-	if err := tx.First(&event, eventID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
+	if !canManageEvent(c, event) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this event"})
+		return
+	}
 
-	// 2. Get all attendance records
-	var attendances []models.Attendance
-	if err := tx.Where("event_id = ?", eventID).Find(&attendances).Error; err != nil {
+	// 2. Keep recurring occurrences from being re-created by their series
+	if event.SeriesID != nil && event.OriginalStartTime != nil {
+		exception := models.EventSeriesException{SeriesID: *event.SeriesID, OriginalStartTime: *event.OriginalStartTime}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&exception).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record series exception"})
+			return
+		}
+	}
+
+	// 3. Soft-delete the event
+	if err := tx.Delete(&event).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		return
+	}
+
+	// 4. The event's week may have been all that held an attendee's streak together, streak awards are
+	// re-evaluated along with the points below
+	if err := services.RecomputeEventStreaks(tx, event); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find attendances"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update attendee streaks"})
+		return
+	}
+
+	// 5. Deduct points from attendees, their attendances are kept so a restore can credit them again.
+	// This runs after the delete so award criteria no longer count the event.
+	adjustment, err := services.RevokeEventPoints(tx, event, c.GetUint("user_id"))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deduct points"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
-	// 3. Collect user IDs and deduct points
-	userIDs := make([]uint, 0, len(attendances))
-	for _, a := range attendances {
-		userIDs = append(userIDs, a.UserID)
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Event deleted",
+		"points_deducted": -adjustment.Delta * adjustment.UsersAdjusted,
+		"awards_revoked":  adjustment.AwardsRevoked,
+		"purges_at":       time.Now().Add(services.EventPurgeAfter()),
+	})
+}
+
+// RestoreEvent brings back a deleted event and credits its attendees with its points again
+func RestoreEvent(c *gin.Context) {
+	eventID := c.Param("eventId")
+
+	tx := database.DB.Begin()
+
+	var event models.Event
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("deleted_at IS NOT NULL").First(&event, eventID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted event not found"})
+		return
+	}
+	if !canManageEvent(c, event) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to restore this event"})
+		return
 	}
 
-	if len(userIDs) > 0 {
-		if err := tx.Model(&models.User{}).Where("id IN ?", userIDs).
-			Update("current_points", gorm.Expr("current_points - ?", event.PointsAllocation)).Error; err != nil {
+	// A series occurrence gets its slot back, unless the slot was re-created in the meantime
+	if event.SeriesID != nil && event.OriginalStartTime != nil {
+		var existing int64
+		if err := tx.Model(&models.Event{}).
+			Where("series_id = ? AND original_start_time = ?", *event.SeriesID, *event.OriginalStartTime).
+			Count(&existing).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deduct points"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check series occurrences"})
+			return
+		}
+		if existing > 0 {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "This occurrence of the series was re-created, delete it before restoring"})
+			return
+		}
+		if err := tx.Where("series_id = ? AND original_start_time = ?", *event.SeriesID, *event.OriginalStartTime).
+			Delete(&models.EventSeriesException{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove series exception"})
 			return
 		}
 	}
 
-	// 4. Delete attendances
-	if err := tx.Where("event_id = ?", eventID).Delete(&models.Attendance{}).Error; err != nil {
+	event.DeletedAt = gorm.DeletedAt{}
+	event.Sequence++
+	if err := tx.Unscoped().Model(&event).Select("deleted_at", "sequence").Updates(&event).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attendances"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore event"})
 		return
 	}
 
-	// 5. Delete event
-	if err := tx.Delete(&models.Event{}, eventID).Error; err != nil {
+	// Streaks first, restoring the points re-evaluates streak awards
+	if err := services.RecomputeEventStreaks(tx, event); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update attendee streaks"})
 		return
 	}
 
-	tx.Commit()
-	c.JSON(http.StatusOK, gin.H{"message": "Event and related data deleted"})
+	adjustment, err := services.RestoreEventPoints(tx, event, c.GetUint("user_id"))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore points"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Event restored",
+		"event":              event,
+		"points_added":       adjustment.Delta * adjustment.UsersAdjusted,
+		"new_awards_granted": adjustment.AwardsGranted,
+	})
+}
+
+// GetDeletedEvents lists the events that were deleted and can still be restored, most recent first (requires admin permission)
+func GetDeletedEvents(c *gin.Context) {
+	type deletedEvent struct {
+		models.Event
+		PurgesAt time.Time `json:"purges_at"`
+	}
+
+	query := database.DB.Unscoped().Model(&models.Event{}).Where("events.deleted_at IS NOT NULL")
+	page, err := pagination.Paginate(c, query, pagination.Key[models.Event]{
+		Column:   "events.deleted_at",
+		Kind:     pagination.KindTime,
+		IDColumn: "events.id",
+		Desc:     true,
+		Value:    func(e models.Event) interface{} { return e.DeletedAt.Time },
+		ID:       func(e models.Event) uint { return e.ID },
+	}, preloadEventRelations)
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve deleted events")
+		return
+	}
+
+	// Tell admins how long each event can still be restored
+	purgeAfter := services.EventPurgeAfter()
+	events := make([]deletedEvent, 0, len(page.Data))
+	for _, event := range page.Data {
+		events = append(events, deletedEvent{Event: event, PurgesAt: event.DeletedAt.Time.Add(purgeAfter)})
+	}
+
+	c.JSON(http.StatusOK, pagination.Page[deletedEvent]{Data: events, Pagination: page.Pagination})
 }
 
 // GetEventAttendees returns basic user info for event attendees
 func GetEventAttendees(c *gin.Context) {
 	eventID := c.Param("eventId")
 
-	var attendees []struct {
-		ID       uint   `json:"id"`
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		PhotoURL string `json:"photo_url"`
+	type attendee struct {
+		AttendanceID uint   `json:"-"`
+		ID           uint   `json:"id"`
+		Name         string `json:"name"`
+		Email        string `json:"email"`
+		PhotoURL     string `json:"photo_url"`
 	}
 
-	err := database.DB.Table("attendances").
-		Select("users.id, users.name, users.email, users.photo_url").
+	query := database.DB.Table("attendances").
+		Select("attendances.id AS attendance_id, users.id, users.name, users.email, users.photo_url").
 		Joins("JOIN users ON users.id = attendances.user_id").
-		Where("attendances.event_id = ?", eventID).
-		Scan(&attendees).Error
+		Where("attendances.event_id = ?", eventID)
+
+	// Attendees are listed in the order they were recorded
+	page, err := pagination.PaginateIfRequested(c, query, pagination.Key[attendee]{
+		IDColumn: "attendances.id",
+		ID:       func(a attendee) uint { return a.AttendanceID },
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attendees"})
+		paginationError(c, err, "Failed to retrieve attendees")
 		return
 	}
 
-	c.JSON(http.StatusOK, attendees)
+	c.JSON(http.StatusOK, page)
 }
 
 // AddAttendances handles adding attendance by user ID or email (supports bulk)
@@ -391,6 +1074,12 @@ func AddAttendances(c *gin.Context) {
 		return
 	}
 
+	// Drafts and cancelled events don't take attendance
+	if !event.AcceptsAttendance() {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Attendance can't be recorded for a %s event", event.Status)})
+		return
+	}
+
 	// Resolve identifiers to valid user IDs
 	resolvedUsers, invalidIdentifiers := resolveValidIdentifiers(input.Identifiers)
 	if len(resolvedUsers) == 0 {
@@ -408,105 +1097,35 @@ func AddAttendances(c *gin.Context) {
 	}
 
 	// Process in transaction
-	tx := database.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// 1. Find existing attendances to avoid duplicates
-	var existingAttendances []models.Attendance
-	if err := tx.Where("user_id IN ? AND event_id = ?", userIDs, eventID).Find(&existingAttendances).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing attendances"})
-		return
-	}
-
-	// Create set of existing user IDs for quick lookup
-	existingUsers := make(map[uint]bool)
-	for _, att := range existingAttendances {
-		existingUsers[att.UserID] = true
-	}
-
-	// Prepare batch insert
-	var newAttendances []models.Attendance
-	var usersToUpdate []uint
-	now := time.Now()
-
-	for _, userID := range userIDs {
-		if existingUsers[userID] {
-			continue
-		}
-
-		newAttendances = append(newAttendances, models.Attendance{
-			UserID:      userID,
-			EventID:     event.ID,
-			ScannedTime: now,
-		})
-		usersToUpdate = append(usersToUpdate, userID)
-	}
-
-	// 2. Batch insert new attendances
-	if len(newAttendances) > 0 {
-		if err := tx.CreateInBatches(newAttendances, 100).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create attendances"})
-			return
-		}
-	}
-
-	// 3. Batch update user points
-	if len(usersToUpdate) > 0 {
-		if err := tx.Model(&models.User{}).
-			Where("id IN ?", usersToUpdate).
-			Update("current_points", gorm.Expr("current_points + ?", event.PointsAllocation)).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user points"})
-			return
-		}
-	}
-
-	// 4. Grant new awards to users who qualify after point update
-	var newAwardsGranted int64 = 0
-	if len(usersToUpdate) > 0 {
-		// Execute raw SQL to insert qualifying awards, avoid adding duplicates
-		result := tx.Exec(`
-			INSERT INTO user_badges (user_id, award_id, created_at, updated_at)
-			SELECT u.id, a.id, ?, ?
-			FROM users u
-			CROSS JOIN awards a
-			LEFT JOIN user_badges ub ON ub.user_id = u.id AND ub.award_id = a.id
-			WHERE u.id IN (?)
-			  AND a.points <= u.current_points
-			  AND ub.user_id IS NULL
-		`, now, now, usersToUpdate)
-
-		if result.Error != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant awards"})
-			return
-		}
-		newAwardsGranted = result.RowsAffected
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+	var recorded services.AttendanceResult
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		recorded, err = services.RecordAttendances(tx, event, services.AttendeesAt(userIDs, time.Now()), models.AttendanceManual, c.GetUint("user_id"))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record attendances"})
 		return
 	}
 
 	// Get details of processed users for response
 	var users []models.User
-	if len(usersToUpdate) > 0 {
-		database.DB.Preload("AwardsEarned").Where("id IN ?", usersToUpdate).Find(&users)
+	if len(recorded.NewAttendees) > 0 {
+		database.DB.Preload("AwardsEarned").Where("id IN ?", recorded.NewAttendees).Find(&users)
+	}
+	awardsEarned, err := earnedAwardsByUser(recorded.AwardsEarned)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve earned awards"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":             "Attendance processed",
-		"new_attendees":       len(newAttendances),
-		"duplicates":          len(userIDs) - len(newAttendances),
-		"points_added":        event.PointsAllocation * len(newAttendances),
-		"new_awards_granted":  newAwardsGranted,
+		"new_attendees":       len(recorded.NewAttendees),
+		"duplicates":          recorded.Duplicates,
+		"points_added":        event.PointsAllocation * len(recorded.NewAttendees),
+		"new_awards_granted":  recorded.AwardsGranted,
+		"awards_earned":       awardsEarned,
 		"processed_users":     users,
 		"invalid_identifiers": invalidIdentifiers,
 	})
@@ -547,41 +1166,247 @@ func DeleteAttendances(c *gin.Context) {
 		userIDs = append(userIDs, user.ID)
 	}
 
-	// Process in transaction
-	tx := database.DB.Begin()
-
-	// Delete attendances
-	result := tx.Where("event_id = ? AND user_id IN ?", eventID, userIDs).
-		Delete(&models.Attendance{})
-	if result.Error != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attendances"})
-		return
-	}
-
-	// Deduct points from users
-	if err := tx.Model(&models.User{}).Where("id IN ?", userIDs).
-		Update("current_points", gorm.Expr("current_points - ?", event.PointsAllocation)).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deduct points"})
+	// Process in transaction, only the users who actually attended lose points
+	var removed []uint
+	var awardChanges services.AwardChanges
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		removed, awardChanges, err = services.RemoveAttendances(tx, event, userIDs, c.GetUint("user_id"))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove attendances"})
 		return
 	}
 
-	tx.Commit()
-
 	// Get details of processed users for response
 	var users []models.User
 	database.DB.Where("id IN ?", userIDs).Find(&users)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":             "Attendance removed",
-		"removed_count":       result.RowsAffected,
-		"points_deducted":     event.PointsAllocation * int(result.RowsAffected),
+		"removed_count":       len(removed),
+		"points_deducted":     event.PointsAllocation * len(removed),
+		"awards_revoked":      awardChanges.Revoked,
 		"processed_users":     users,
 		"invalid_identifiers": invalidIdentifiers,
 	})
 }
 
+// earnedAwards lists the awards a user newly earned
+type earnedAwards struct {
+	UserID uint           `json:"user_id"`
+	Awards []models.Award `json:"awards"`
+}
+
+// Helper function to load the awards behind award IDs grouped by user, for telling users what they earned
+func earnedAwardsByUser(awardIDsByUser map[uint][]uint) ([]earnedAwards, error) {
+	earned := []earnedAwards{}
+	if len(awardIDsByUser) == 0 {
+		return earned, nil
+	}
+
+	var awardIDs []uint
+	userIDs := make([]uint, 0, len(awardIDsByUser))
+	for userID, ids := range awardIDsByUser {
+		userIDs = append(userIDs, userID)
+		awardIDs = append(awardIDs, ids...)
+	}
+	var awardList []models.Award
+	if err := database.DB.Where("id IN ?", awardIDs).Find(&awardList).Error; err != nil {
+		return nil, err
+	}
+	awardsByID := make(map[uint]models.Award, len(awardList))
+	for _, award := range awardList {
+		awardsByID[award.ID] = award
+	}
+
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	for _, userID := range userIDs {
+		entry := earnedAwards{UserID: userID, Awards: []models.Award{}}
+		for _, awardID := range awardIDsByUser[userID] {
+			if award, ok := awardsByID[awardID]; ok {
+				entry.Awards = append(entry.Awards, award)
+			}
+		}
+		earned = append(earned, entry)
+	}
+	return earned, nil
+}
+
+// Helper function to preload the relations returned with events
+func preloadEventRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Organizer").Preload("Awards").Preload("Category").Preload("Venue").Preload("Tags")
+}
+
+// Helper function to report pagination failures, bad limits and cursors are the client's fault
+func paginationError(c *gin.Context, err error, message string) {
+	if errors.Is(err, pagination.ErrInvalidLimit) || errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// Helper function to validate the RSVP related fields of an event, returns an error message or ""
+func validateRSVPSettings(capacity *int, deadline *time.Time, endTime *time.Time) string {
+	if capacity != nil && *capacity <= 0 {
+		return "capacity must be a positive integer"
+	}
+	if deadline != nil && endTime != nil && deadline.After(*endTime) {
+		return "rsvp_deadline must not be after end_time"
+	}
+	return ""
+}
+
+// Helper function to validate how an event is attended, returns an error message or ""
+func validateEventMode(mode string, joinURL string) string {
+	if !isEventMode(mode) {
+		return "mode must be one of 'in_person', 'online' or 'hybrid'"
+	}
+	if joinURL == "" {
+		return ""
+	}
+	if mode == string(models.EventInPerson) {
+		return "join_url can only be set for online and hybrid events"
+	}
+	if parsed, err := url.Parse(joinURL); err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return "join_url must be an http(s) URL"
+	}
+	return ""
+}
+
+// Helper function to build the ETag of an event, the sequence is bumped on every change so it doubles as the version
+func eventETag(event models.Event) string {
+	return fmt.Sprintf("\"%d-%d\"", event.ID, event.Sequence)
+}
+
+// Helper function to refuse event edits without an If-Match header, without it two organizers editing at once
+// would silently overwrite each other. Reports whether the edit may go ahead.
+func requireIfMatch(c *gin.Context) bool {
+	if strings.TrimSpace(c.GetHeader("If-Match")) == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Send the ETag of the event as If-Match, it is returned when getting the event"})
+		return false
+	}
+	return true
+}
+
+// Helper function to refuse edits of an event that changed since the client got it or that is archived, the
+// event must be locked so it can't change until the edit is saved. Reports whether the edit may go ahead.
+func checkEventEditable(c *gin.Context, event models.Event) bool {
+	if !ifMatchesEvent(c, event) {
+		c.Header("ETag", eventETag(event))
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "The event was modified by someone else, reload it and try again"})
+		return false
+	}
+	if event.Status == string(models.EventArchived) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Archived events can't be modified"})
+		return false
+	}
+	return true
+}
+
+// Helper function to check the If-Match header against the current version of an event, requests without
+// the header are let through (edits check requireIfMatch beforehand)
+func ifMatchesEvent(c *gin.Context, event models.Event) bool {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+	current := eventETag(event)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == current {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to check an event's capacity fits its venue, returns an error message or ""
+func validateVenueCapacity(venue models.Venue, capacity *int) string {
+	if venue.Capacity == nil {
+		return ""
+	}
+	if capacity == nil || *capacity > *venue.Capacity {
+		return fmt.Sprintf("capacity must be set and at most %d, the capacity of %s", *venue.Capacity, venue.Name)
+	}
+	return ""
+}
+
+// Helper function to check whether the current user may manage an event (its organizer, staff or admins)
+func canManageEvent(c *gin.Context, event models.Event) bool {
+	return canManageOrganizedBy(c, event.OrganizerID)
+}
+
+// Helper function to check whether the current user is the given organizer, staff or an admin
+func canManageOrganizedBy(c *gin.Context, organizerID uint) bool {
+	return isStaff(c) || organizerID == c.GetUint("user_id")
+}
+
+// Helper function to check whether the current user is staff or an admin
+func isStaff(c *gin.Context) bool {
+	role := c.GetString("role")
+	return role == string(models.RoleAdmin) || role == string(models.RoleStaff)
+}
+
+// Helper function to check a string against the known event statuses
+func isEventStatus(status string) bool {
+	switch models.EventStatus(status) {
+	case models.EventDraft, models.EventPublished, models.EventCancelled, models.EventArchived:
+		return true
+	}
+	return false
+}
+
+// Helper function to check a string against the known event modes
+func isEventMode(mode string) bool {
+	switch models.EventMode(mode) {
+	case models.EventInPerson, models.EventOnline, models.EventHybrid:
+		return true
+	}
+	return false
+}
+
+// Helper function to let everyone who RSVP'd know that an event was cancelled
+func notifyEventCancelled(event models.Event) {
+	var users []models.User
+	err := database.DB.Joins("JOIN rsvps ON rsvps.user_id = users.id").
+		Where("rsvps.event_id = ? AND rsvps.status <> ?", event.ID, models.RSVPCancelled).
+		Find(&users).Error
+	if err != nil {
+		return
+	}
+
+	body := "Unfortunately this event has been cancelled."
+	if event.CancellationReason != "" {
+		body += "\nReason: " + event.CancellationReason
+	}
+	for _, user := range users {
+		utils.SendEmail(user.Email, fmt.Sprintf("%s has been cancelled", event.Name), body)
+	}
+}
+
+// Helper function to compare optional IDs
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Helper function to flatten repeated and comma-separated query values
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
+
 // Helper function to resolve mixed identifiers (IDs or emails) to valid user records
 func resolveValidIdentifiers(identifiers []string) ([]models.User, []string) {
 	var users []models.User
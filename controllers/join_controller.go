@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/meetings"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Joining an online event counts as attending from this long before it starts until it ends
+const joinWindowLead = 15 * time.Minute
+
+// Meeting webhooks larger than this are refused
+const maxWebhookBytes = 1 << 20
+
+// GetEventJoinLink returns the link to join an online or hybrid event. Attendees get a personal tracked link
+// that records their attendance, and only once they RSVP'd. People managing the event get the stream link itself.
+func GetEventJoinLink(c *gin.Context) {
+	eventID := c.Param("eventId")
+	userID := c.GetUint("user_id")
+
+	var event models.Event
+	if err := database.DB.First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if event.Status == string(models.EventDraft) && !canManageEvent(c, event) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if !event.IsStreamed() || event.JoinURL == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "The event can't be joined online"})
+		return
+	}
+
+	if canManageEvent(c, event) {
+		c.JSON(http.StatusOK, gin.H{
+			"join_url":   event.JoinURL,
+			"meeting_id": event.MeetingID,
+		})
+		return
+	}
+
+	if event.Status == string(models.EventCancelled) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The event was cancelled"})
+		return
+	}
+	var rsvp models.RSVP
+	if err := database.DB.Where("event_id = ? AND user_id = ? AND status = ?", event.ID, userID, models.RSVPGoing).
+		First(&rsvp).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "RSVP to the event to get its join link"})
+		return
+	}
+
+	link, err := findOrCreateJoinLink(event.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create join link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"join_url":     fmt.Sprintf("/join/%s", link.Token),
+		"click_count":  link.ClickCount,
+		"last_used_at": link.LastUsedAt,
+	})
+}
+
+// FollowJoinLink records the attendance of the owner of a tracked join link and redirects them to the stream
+// (authenticated by the secret token in the URL, so it works straight from calendar apps and emails)
+func FollowJoinLink(c *gin.Context) {
+	var link models.JoinLink
+	token := c.Param("token")
+	if token == "" || database.DB.Where("token = ?", token).First(&link).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Join link not found"})
+		return
+	}
+
+	var event models.Event
+	if err := database.DB.First(&event, link.EventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if !event.IsStreamed() || event.JoinURL == "" || event.Status == string(models.EventCancelled) {
+		c.JSON(http.StatusGone, gin.H{"error": "The event can no longer be joined online"})
+		return
+	}
+
+	now := time.Now()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Links stop working once their owner cancels or is moved back to the waitlist
+		var rsvp models.RSVP
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("event_id = ? AND user_id = ? AND status = ?", event.ID, link.UserID, models.RSVPGoing).
+			First(&rsvp).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errJoinLinkRevoked
+			}
+			return err
+		}
+		if err := tx.Model(&link).Updates(map[string]interface{}{
+			"click_count":  gorm.Expr("click_count + 1"),
+			"last_used_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		// Opening the link days early doesn't count as attending, but it still leads to the stream
+		if !event.AcceptsAttendance() || !inJoinWindow(event, now) {
+			return nil
+		}
+		_, err := services.RecordAttendances(tx, event, services.AttendeesAt([]uint{link.UserID}, now), models.AttendanceJoinLink, 0)
+		return err
+	})
+	if errors.Is(err, errJoinLinkRevoked) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are no longer going to this event, RSVP again to join it"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record attendance"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, event.JoinURL)
+}
+
+// MeetingWebhook records the attendance a meeting platform reports for the events linked to the meeting
+// (authenticated by the provider's signature check)
+func MeetingWebhook(c *gin.Context) {
+	provider, ok := meetings.Lookup(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown meeting provider"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBytes))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Webhook body too large"})
+		return
+	}
+	report, err := provider.ParseWebhook(c.Request, body)
+	if errors.Is(err, meetings.ErrInvalidSignature) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var events []models.Event
+	if err := database.DB.Where("meeting_id = ? AND mode IN ?", report.MeetingID,
+		[]string{string(models.EventOnline), string(models.EventHybrid)}).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	// Platforms retry failed deliveries, so meetings we don't know about are acknowledged and ignored
+	if len(events) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "No event uses this meeting", "recorded": 0})
+		return
+	}
+
+	// Resolve the participants to users
+	emails := make([]string, 0, len(report.Participants))
+	for _, participant := range report.Participants {
+		emails = append(emails, strings.ToLower(participant.Email))
+	}
+	var users []models.User
+	if len(emails) > 0 {
+		if err := database.DB.Where("LOWER(email) IN ?", emails).Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve participants"})
+			return
+		}
+	}
+	userIDsByEmail := make(map[string]uint, len(users))
+	for _, user := range users {
+		userIDsByEmail[strings.ToLower(user.Email)] = user.ID
+	}
+
+	// A recurring meeting is shared by several events, each participant attended the one they joined during.
+	// Their attendance is dated when they joined, platforms may deliver the report much later.
+	attendees := make(map[uint][]services.Attendee)
+	var unknown []string
+	for _, participant := range report.Participants {
+		userID, found := userIDsByEmail[strings.ToLower(participant.Email)]
+		if !found {
+			unknown = append(unknown, participant.Email)
+			continue
+		}
+		for _, event := range events {
+			if event.AcceptsAttendance() && inJoinWindow(event, participant.JoinedAt) {
+				attendees[event.ID] = append(attendees[event.ID], services.Attendee{UserID: userID, ScannedTime: participant.JoinedAt})
+				break
+			}
+		}
+	}
+	// Participants who rejoined count from their first join
+	for _, eventAttendees := range attendees {
+		sort.SliceStable(eventAttendees, func(i, j int) bool {
+			return eventAttendees[i].ScannedTime.Before(eventAttendees[j].ScannedTime)
+		})
+	}
+
+	recorded := 0
+	var awardsGranted int64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, event := range events {
+			result, err := services.RecordAttendances(tx, event, attendees[event.ID], models.AttendanceWebhook, 0)
+			if err != nil {
+				return err
+			}
+			recorded += len(result.NewAttendees)
+			awardsGranted += result.AwardsGranted
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record attendances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Attendance processed",
+		"recorded":             recorded,
+		"awards_granted":       awardsGranted,
+		"unknown_participants": unknown,
+	})
+}
+
+var errJoinLinkRevoked = errors.New("join link owner is no longer going")
+
+// Helper function to check whether joining at the given time counts as attending, events without a
+// schedule accept attendance at any time
+func inJoinWindow(event models.Event, at time.Time) bool {
+	if event.StartTime == nil || event.EndTime == nil {
+		return true
+	}
+	return !at.Before(event.StartTime.Add(-joinWindowLead)) && !at.After(*event.EndTime)
+}
+
+// Helper function to get a user's join link to an event, creating it on first use
+func findOrCreateJoinLink(eventID, userID uint) (models.JoinLink, error) {
+	var link models.JoinLink
+	if err := database.DB.Where("event_id = ? AND user_id = ?", eventID, userID).First(&link).Error; err == nil {
+		return link, nil
+	}
+
+	token, err := utils.GenerateSecureToken(24)
+	if err != nil {
+		return link, err
+	}
+	link = models.JoinLink{EventID: eventID, UserID: userID, Token: token}
+	// Two requests may race to create the link, the loser reads the winner's
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error; err != nil {
+		return link, err
+	}
+	if link.ID == 0 {
+		err = database.DB.Where("event_id = ? AND user_id = ?", eventID, userID).First(&link).Error
+	}
+	return link, err
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/services"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+// GetLeaderboard ranks users by points, optionally within a cohort (grad_year), department, term (term_id, or
+// "current"), time range (from/to, RFC3339) or category. Users with the same points share a rank. The caller's
+// own entry is included even when it is outside the top, unless they opted out of leaderboards.
+func GetLeaderboard(c *gin.Context) {
+	queryParams := struct {
+		GradYear   string `form:"grad_year"`
+		Department string `form:"department"`
+		TermID     string `form:"term_id"`
+		From       string `form:"from"`
+		To         string `form:"to"`
+		CategoryID string `form:"category_id"`
+		Limit      string `form:"limit"`
+	}{}
+	if err := c.ShouldBindQuery(&queryParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	filter := services.LeaderboardFilter{Department: queryParams.Department}
+	if queryParams.GradYear != "" {
+		gradYear, err := strconv.Atoi(queryParams.GradYear)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "grad_year must be a year"})
+			return
+		}
+		filter.GradYear = &gradYear
+	}
+	if queryParams.TermID == "current" {
+		term, err := services.CurrentTerm(database.DB)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve the current term"})
+			return
+		}
+		if term == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No term is in progress"})
+			return
+		}
+		filter.TermID = &term.ID
+	} else if queryParams.TermID != "" {
+		termID, err := strconv.Atoi(queryParams.TermID)
+		if err != nil || termID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "term_id must be a term ID or current"})
+			return
+		}
+		id := uint(termID)
+		filter.TermID = &id
+	}
+	if queryParams.From != "" {
+		from, err := time.Parse(time.RFC3339, queryParams.From)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from format. Use RFC3339 (e.g., 2023-10-01T00:00:00Z)"})
+			return
+		}
+		filter.From = &from
+	}
+	if queryParams.To != "" {
+		to, err := time.Parse(time.RFC3339, queryParams.To)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to format. Use RFC3339 (e.g., 2023-10-01T00:00:00Z)"})
+			return
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+	if queryParams.CategoryID != "" {
+		categoryID, err := strconv.Atoi(queryParams.CategoryID)
+		if err != nil || categoryID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "category_id must be a category ID"})
+			return
+		}
+		id := uint(categoryID)
+		filter.CategoryID = &id
+	}
+	limit := defaultLeaderboardLimit
+	if queryParams.Limit != "" {
+		var err error
+		limit, err = strconv.Atoi(queryParams.Limit)
+		if err != nil || limit < 1 || limit > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+	}
+
+	board, err := services.GetLeaderboard(database.DB, filter)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute leaderboard"})
+		return
+	}
+
+	// The caller is missing when they opted out or don't match the filter
+	var me *services.LeaderboardEntry
+	if entry, ok := board.Position(c.GetUint("user_id")); ok {
+		me = &entry
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":         board.Top(limit),
+		"me":           me,
+		"total":        len(board.Entries),
+		"ranked_by":    leaderboardRanking(filter),
+		"generated_at": board.GeneratedAt,
+	})
+}
+
+// Helper function to describe what a leaderboard ranks by
+func leaderboardRanking(filter services.LeaderboardFilter) string {
+	if filter.TermID != nil {
+		return "term_points"
+	}
+	if filter.UsesLedger() {
+		return "points_earned"
+	}
+	return "lifetime_points"
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
+	"gorm.io/gorm"
+)
+
+// GetUserPointsHistory lists the changes to a user's balance from the points ledger, newest first,
+// with the balance after each change
+func GetUserPointsHistory(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	queryParams := struct {
+		Reason  []string `form:"reason"`   // repeatable or comma-separated
+		EventID string   `form:"event_id"` // only changes related to this event
+	}{}
+	if err := c.ShouldBindQuery(&queryParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	// The running balance is computed over the whole ledger before filtering so it is right on every page
+	ledger := database.DB.Model(&models.PointTransaction{}).
+		Select("point_transactions.*, SUM(delta) OVER (ORDER BY id) AS balance_after").
+		Where("user_id = ?", user.ID)
+	query := database.DB.Table("(?) AS point_transactions", ledger).
+		Select("point_transactions.*, events.name AS event_name").
+		Joins("LEFT JOIN events ON events.id = point_transactions.event_id")
+
+	if reasons := splitQueryValues(queryParams.Reason); len(reasons) > 0 {
+		query = query.Where("point_transactions.reason IN ?", reasons)
+	}
+	if queryParams.EventID != "" {
+		eventID, err := strconv.Atoi(queryParams.EventID)
+		if err != nil || eventID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "event_id must be an event ID"})
+			return
+		}
+		query = query.Where("point_transactions.event_id = ?", eventID)
+	}
+
+	page, err := pagination.Paginate(c, query, pagination.Key[models.PointTransaction]{
+		IDColumn: "point_transactions.id",
+		Desc:     true,
+		ID:       func(t models.PointTransaction) uint { return t.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve points history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"current_points": user.CurrentPoints,
+		"data":           page.Data,
+		"pagination":     page.Pagination,
+	})
+}
+
+// ReconcilePoints checks every balance against the attendances and ledger, by default only reporting
+// the discrepancies, pass dry_run=false to correct them (requires admin permission)
+func ReconcilePoints(c *gin.Context) {
+	dryRun := c.Query("dry_run") != "false"
+	run, err := services.ReconcilePoints(database.DB, dryRun, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile points"})
+		return
+	}
+
+	if err := database.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("user_id") }).
+		First(&run, run.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload reconciliation run"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// GetReconciliationRuns lists the reconciliation runs, newest first (requires admin permission)
+func GetReconciliationRuns(c *gin.Context) {
+	page, err := pagination.Paginate(c, database.DB.Model(&models.ReconciliationRun{}), pagination.Key[models.ReconciliationRun]{
+		IDColumn: "reconciliation_runs.id",
+		Desc:     true,
+		ID:       func(r models.ReconciliationRun) uint { return r.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve reconciliation runs")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetReconciliationRun retrieves a reconciliation run with its discrepancies (requires admin permission)
+func GetReconciliationRun(c *gin.Context) {
+	var run models.ReconciliationRun
+	if err := database.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("user_id") }).
+		First(&run, c.Param("runId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reconciliation run not found"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
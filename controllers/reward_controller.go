@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetRewards lists the rewards catalog. Everyone sees the rewards that can be redeemed now, staff can pass
+// all=true to include inactive rewards and those outside their availability window.
+func GetRewards(c *gin.Context) {
+	query := database.DB.Model(&models.Reward{})
+	if !(isStaff(c) && c.Query("all") == "true") {
+		now := time.Now()
+		query = query.Where("active = ?", true).
+			Where("available_from IS NULL OR available_from <= ?", now).
+			Where("available_until IS NULL OR available_until > ?", now)
+	}
+
+	page, err := pagination.Paginate(c, query, pagination.Key[models.Reward]{
+		Column:   "cost",
+		Kind:     pagination.KindInt,
+		IDColumn: "id",
+		Value:    func(r models.Reward) interface{} { return r.Cost },
+		ID:       func(r models.Reward) uint { return r.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve rewards")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetReward retrieves a single reward, inactive ones are only visible to staff
+func GetReward(c *gin.Context) {
+	var reward models.Reward
+	if err := database.DB.First(&reward, c.Param("rewardId")).Error; err != nil || (!reward.Active && !isStaff(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reward not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reward)
+}
+
+// CreateReward adds a reward to the catalog (requires admin permission)
+func CreateReward(c *gin.Context) {
+	var input struct {
+		Name           string     `json:"name" binding:"required"`
+		Description    string     `json:"description"`
+		ImageURL       string     `json:"image_url"`
+		Cost           int        `json:"cost" binding:"required"`
+		Stock          *int       `json:"stock"` // Omit for unlimited
+		AvailableFrom  *time.Time `json:"available_from"`
+		AvailableUntil *time.Time `json:"available_until"`
+		Active         *bool      `json:"active"` // Defaults to true
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reward := models.Reward{
+		Name:           strings.TrimSpace(input.Name),
+		Description:    input.Description,
+		ImageURL:       input.ImageURL,
+		Cost:           input.Cost,
+		Stock:          input.Stock,
+		AvailableFrom:  input.AvailableFrom,
+		AvailableUntil: input.AvailableUntil,
+		Active:         input.Active == nil || *input.Active,
+	}
+	if msg := validateReward(reward); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := database.DB.Create(&reward).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reward"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reward)
+}
+
+// UpdateReward partially updates a reward, redemptions already made keep the cost they were made at (requires
+// admin permission)
+func UpdateReward(c *gin.Context) {
+	var input struct {
+		Name           *string                   `json:"name"`
+		Description    *string                   `json:"description"`
+		ImageURL       *string                   `json:"image_url"`
+		Cost           *int                      `json:"cost"`
+		Stock          utils.Nullable[int]       `json:"stock"` // null means unlimited
+		AvailableFrom  utils.Nullable[time.Time] `json:"available_from"`
+		AvailableUntil utils.Nullable[time.Time] `json:"available_until"`
+		Active         *bool                     `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reward models.Reward
+	var validationMsg string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Locked so the stock doesn't change under a concurrent redemption
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, c.Param("rewardId")).Error; err != nil {
+			return err
+		}
+
+		if input.Name != nil {
+			reward.Name = strings.TrimSpace(*input.Name)
+		}
+		if input.Description != nil {
+			reward.Description = *input.Description
+		}
+		if input.ImageURL != nil {
+			reward.ImageURL = *input.ImageURL
+		}
+		if input.Cost != nil {
+			reward.Cost = *input.Cost
+		}
+		reward.Stock = input.Stock.Or(reward.Stock)
+		reward.AvailableFrom = input.AvailableFrom.Or(reward.AvailableFrom)
+		reward.AvailableUntil = input.AvailableUntil.Or(reward.AvailableUntil)
+		if input.Active != nil {
+			reward.Active = *input.Active
+		}
+		if validationMsg = validateReward(reward); validationMsg != "" {
+			return errRewardInvalid
+		}
+
+		return tx.Save(&reward).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reward not found"})
+		return
+	}
+	if errors.Is(err, errRewardInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationMsg})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reward"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reward)
+}
+
+// RedeemReward spends the current user's points on a reward, the response carries the pickup code to show staff
+func RedeemReward(c *gin.Context) {
+	rewardID, err := strconv.Atoi(c.Param("rewardId"))
+	if err != nil || rewardID <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reward not found"})
+		return
+	}
+
+	redemption, err := services.RedeemReward(database.DB, uint(rewardID), c.GetUint("user_id"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reward not found"})
+		return
+	case errors.Is(err, services.ErrRewardUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": "This reward can't be redeemed right now"})
+		return
+	case errors.Is(err, services.ErrRewardOutOfStock):
+		c.JSON(http.StatusConflict, gin.H{"error": "This reward is out of stock"})
+		return
+	case errors.Is(err, services.ErrInsufficientPoints):
+		c.JSON(http.StatusConflict, gin.H{"error": "You don't have enough points for this reward"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem reward"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, redemption)
+}
+
+// GetRedemptions lists redemptions, newest first, filterable by status and user_id (requires staff permission)
+func GetRedemptions(c *gin.Context) {
+	queryParams := struct {
+		Status []string `form:"status"` // repeatable or comma-separated
+		UserID string   `form:"user_id"`
+	}{}
+	if err := c.ShouldBindQuery(&queryParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	query := database.DB.Model(&models.Redemption{}).Preload("Reward")
+	if statuses := splitQueryValues(queryParams.Status); len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if queryParams.UserID != "" {
+		userID, err := strconv.Atoi(queryParams.UserID)
+		if err != nil || userID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a user ID"})
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+
+	paginateRedemptions(c, query)
+}
+
+// GetUserRedemptions lists a user's redemptions, newest first
+func GetUserRedemptions(c *gin.Context) {
+	query := database.DB.Model(&models.Redemption{}).Preload("Reward").Where("user_id = ?", c.Param("id"))
+	paginateRedemptions(c, query)
+}
+
+// GetRedemptionByCode looks up a redemption by its pickup code (requires staff permission)
+func GetRedemptionByCode(c *gin.Context) {
+	var redemption models.Redemption
+	if err := database.DB.Preload("Reward").Where("pickup_code = ?", strings.ToUpper(c.Param("code"))).
+		First(&redemption).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redemption not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, redemption)
+}
+
+// FulfillRedemption marks the redemption with a pickup code as handed over (requires staff permission)
+func FulfillRedemption(c *gin.Context) {
+	var input struct {
+		PickupCode string `json:"pickup_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(input.PickupCode))
+	redemption, err := services.FulfillRedemption(database.DB, code, c.GetUint("user_id"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No redemption has this pickup code"})
+		return
+	case errors.Is(err, services.ErrRedemptionNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "This redemption was already " + redemption.Status})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fulfill redemption"})
+		return
+	}
+
+	c.JSON(http.StatusOK, redemption)
+}
+
+// CancelRedemption cancels a pending redemption and refunds its points, users can cancel their own and staff
+// any of them
+func CancelRedemption(c *gin.Context) {
+	var redemption models.Redemption
+	if err := database.DB.First(&redemption, c.Param("redemptionId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redemption not found"})
+		return
+	}
+	if redemption.UserID != c.GetUint("user_id") && !isStaff(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to cancel this redemption"})
+		return
+	}
+
+	redemption, err := services.CancelRedemption(database.DB, redemption.ID, c.GetUint("user_id"))
+	if errors.Is(err, services.ErrRedemptionNotPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This redemption was already " + redemption.Status})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel redemption"})
+		return
+	}
+
+	c.JSON(http.StatusOK, redemption)
+}
+
+var errRewardInvalid = errors.New("invalid reward")
+
+// Helper function to paginate redemptions, newest first
+func paginateRedemptions(c *gin.Context, query *gorm.DB) {
+	page, err := pagination.Paginate(c, query, pagination.Key[models.Redemption]{
+		IDColumn: "id",
+		Desc:     true,
+		ID:       func(r models.Redemption) uint { return r.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve redemptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// Helper function to validate a reward, returns an error message or ""
+func validateReward(reward models.Reward) string {
+	if reward.Name == "" {
+		return "name must not be empty"
+	}
+	if reward.Cost <= 0 {
+		return "cost must be positive"
+	}
+	if reward.Stock != nil && *reward.Stock < 0 {
+		return "stock must not be negative"
+	}
+	if reward.AvailableFrom != nil && reward.AvailableUntil != nil && !reward.AvailableFrom.Before(*reward.AvailableUntil) {
+		return "available_from must be before available_until"
+	}
+	return ""
+}
@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateRSVP registers the current user for an event, placing them on the waitlist when it is full
+func CreateRSVP(c *gin.Context) {
+	eventID := c.Param("eventId")
+	userID := c.GetUint("user_id")
+	now := time.Now()
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	// Lock the event row so concurrent RSVPs can't oversubscribe the capacity
+	var event models.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	if event.Status != string(models.EventPublished) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Only published events accept RSVPs"})
+		return
+	}
+	if event.RSVPDeadline != nil && now.After(*event.RSVPDeadline) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The RSVP deadline for this event has passed"})
+		return
+	}
+	if event.EndTime != nil && now.After(*event.EndTime) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event has already ended"})
+		return
+	}
+
+	// Re-use a cancelled RSVP if the user had one, otherwise start a new one
+	var rsvp models.RSVP
+	err := tx.Where("event_id = ? AND user_id = ?", event.ID, userID).First(&rsvp).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing RSVP"})
+		return
+	}
+	if err == nil && rsvp.Status != string(models.RSVPCancelled) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already RSVP'd to this event", "rsvp": rsvp})
+		return
+	}
+
+	var going int64
+	if err := tx.Model(&models.RSVP{}).
+		Where("event_id = ? AND status = ?", event.ID, models.RSVPGoing).
+		Count(&going).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count RSVPs"})
+		return
+	}
+
+	rsvp.UserID = userID
+	rsvp.EventID = event.ID
+	rsvp.Status = string(models.RSVPGoing)
+	rsvp.RespondedAt = now
+	if event.Capacity != nil && going >= int64(*event.Capacity) {
+		rsvp.Status = string(models.RSVPWaitlisted)
+	}
+
+	if err := tx.Save(&rsvp).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save RSVP"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	response := gin.H{"rsvp": rsvp}
+	if rsvp.Status == string(models.RSVPWaitlisted) {
+		response["waitlist_position"] = waitlistPosition(database.DB, rsvp)
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetMyRSVP returns the current user's RSVP for an event
+func GetMyRSVP(c *gin.Context) {
+	eventID := c.Param("eventId")
+	userID := c.GetUint("user_id")
+
+	var rsvp models.RSVP
+	if err := database.DB.Where("event_id = ? AND user_id = ?", eventID, userID).First(&rsvp).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RSVP not found"})
+		return
+	}
+
+	response := gin.H{"rsvp": rsvp}
+	if rsvp.Status == string(models.RSVPWaitlisted) {
+		response["waitlist_position"] = waitlistPosition(database.DB, rsvp)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelRSVP cancels the current user's RSVP and promotes the waitlist if a spot opened up
+func CancelRSVP(c *gin.Context) {
+	eventID := c.Param("eventId")
+	userID := c.GetUint("user_id")
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	var event models.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	var rsvp models.RSVP
+	if err := tx.Where("event_id = ? AND user_id = ? AND status <> ?", event.ID, userID, models.RSVPCancelled).
+		First(&rsvp).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "RSVP not found"})
+		return
+	}
+
+	wasGoing := rsvp.Status == string(models.RSVPGoing)
+	rsvp.Status = string(models.RSVPCancelled)
+	if err := tx.Save(&rsvp).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel RSVP"})
+		return
+	}
+
+	// Only a confirmed spot frees capacity for the waitlist
+	var promoted []models.RSVP
+	if wasGoing {
+		var err error
+		promoted, err = promoteWaitlist(tx, event)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote waitlist"})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	notifyPromoted(event, promoted)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "RSVP cancelled",
+		"promoted_count": len(promoted),
+	})
+}
+
+// GetEventRSVPs lists the RSVPs of an event (organizer, staff or admin only)
+func GetEventRSVPs(c *gin.Context) {
+	eventID := c.Param("eventId")
+
+	var event models.Event
+	if err := database.DB.First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if !canManageEvent(c, event) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view RSVPs for this event"})
+		return
+	}
+
+	query := database.DB.Model(&models.RSVP{}).Where("event_id = ?", event.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// RSVPs are listed in waitlist order
+	page, err := pagination.Paginate(c, query, pagination.Key[models.RSVP]{
+		Column:   "rsvps.responded_at",
+		Kind:     pagination.KindTime,
+		IDColumn: "rsvps.id",
+		Value:    func(r models.RSVP) interface{} { return r.RespondedAt },
+		ID:       func(r models.RSVP) uint { return r.ID },
+	}, func(db *gorm.DB) *gorm.DB { return db.Preload("User") })
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve RSVPs")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetEventRSVPStats compares RSVPs against actual attendance to measure no-show rates
+func GetEventRSVPStats(c *gin.Context) {
+	eventID := c.Param("eventId")
+
+	var event models.Event
+	if err := database.DB.First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if !canManageEvent(c, event) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view RSVPs for this event"})
+		return
+	}
+
+	var stats struct {
+		Going            int64 `json:"going"`
+		Waitlisted       int64 `json:"waitlisted"`
+		Cancelled        int64 `json:"cancelled"`
+		Attended         int64 `json:"attended"`
+		RSVPAttended     int64 `json:"rsvp_attended"`
+		NoShows          int64 `json:"no_shows"`
+		WalkIns          int64 `json:"walk_ins"`
+		WaitlistAttended int64 `json:"waitlist_attended"`
+	}
+
+	err := database.DB.Raw(`
+		SELECT
+			COUNT(*) FILTER (WHERE r.status = 'going') AS going,
+			COUNT(*) FILTER (WHERE r.status = 'waitlisted') AS waitlisted,
+			COUNT(*) FILTER (WHERE r.status = 'cancelled') AS cancelled,
+			COUNT(*) FILTER (WHERE r.status = 'going' AND a.id IS NOT NULL) AS rsvp_attended,
+			COUNT(*) FILTER (WHERE r.status = 'going' AND a.id IS NULL) AS no_shows,
+			COUNT(*) FILTER (WHERE r.status = 'waitlisted' AND a.id IS NOT NULL) AS waitlist_attended
+		FROM rsvps r
+		LEFT JOIN attendances a ON a.event_id = r.event_id AND a.user_id = r.user_id
+		WHERE r.event_id = ?
+	`, event.ID).Scan(&stats).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute RSVP statistics"})
+		return
+	}
+
+	var attendance struct {
+		Attended int64
+		WalkIns  int64
+	}
+	err = database.DB.Raw(`
+		SELECT
+			COUNT(*) AS attended,
+			COUNT(*) FILTER (WHERE r.id IS NULL) AS walk_ins
+		FROM attendances a
+		LEFT JOIN rsvps r ON r.event_id = a.event_id AND r.user_id = a.user_id AND r.status = 'going'
+		WHERE a.event_id = ?
+	`, event.ID).Scan(&attendance).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute attendance statistics"})
+		return
+	}
+	stats.Attended = attendance.Attended
+	stats.WalkIns = attendance.WalkIns
+
+	// No-shows only make sense once the event is over
+	ended := event.EndTime != nil && time.Now().After(*event.EndTime)
+	var noShowRate *float64
+	if ended && stats.Going > 0 {
+		rate := float64(stats.NoShows) / float64(stats.Going)
+		noShowRate = &rate
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event_id":     event.ID,
+		"capacity":     event.Capacity,
+		"event_ended":  ended,
+		"stats":        stats,
+		"no_show_rate": noShowRate,
+	})
+}
+
+// Helper function to promote waitlisted RSVPs into any free spots, the event row must be locked by the caller
+func promoteWaitlist(tx *gorm.DB, event models.Event) ([]models.RSVP, error) {
+	query := tx.Where("event_id = ? AND status = ?", event.ID, models.RSVPWaitlisted).
+		Order("responded_at asc, id asc")
+
+	if event.Capacity != nil {
+		var going int64
+		if err := tx.Model(&models.RSVP{}).
+			Where("event_id = ? AND status = ?", event.ID, models.RSVPGoing).
+			Count(&going).Error; err != nil {
+			return nil, err
+		}
+
+		open := int64(*event.Capacity) - going
+		if open <= 0 {
+			return nil, nil
+		}
+		query = query.Limit(int(open))
+	}
+
+	var promoted []models.RSVP
+	if err := query.Find(&promoted).Error; err != nil {
+		return nil, err
+	}
+	if len(promoted) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(promoted))
+	for i := range promoted {
+		ids = append(ids, promoted[i].ID)
+		promoted[i].Status = string(models.RSVPGoing)
+	}
+	if err := tx.Model(&models.RSVP{}).Where("id IN ?", ids).
+		Update("status", models.RSVPGoing).Error; err != nil {
+		return nil, err
+	}
+
+	return promoted, nil
+}
+
+// Helper function to let users know they got a spot off the waitlist
+func notifyPromoted(event models.Event, promoted []models.RSVP) {
+	if len(promoted) == 0 {
+		return
+	}
+
+	userIDs := make([]uint, 0, len(promoted))
+	for _, rsvp := range promoted {
+		userIDs = append(userIDs, rsvp.UserID)
+	}
+
+	var users []models.User
+	if err := database.DB.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return
+	}
+	for _, user := range users {
+		utils.SendEmail(user.Email,
+			fmt.Sprintf("You're off the waitlist for %s", event.Name),
+			"A spot opened up and your RSVP is now confirmed.")
+	}
+}
+
+// Helper function to compute a waitlisted RSVP's 1-based position in the queue
+func waitlistPosition(db *gorm.DB, rsvp models.RSVP) int64 {
+	var ahead int64
+	db.Model(&models.RSVP{}).
+		Where("event_id = ? AND status = ?", rsvp.EventID, models.RSVPWaitlisted).
+		Where("responded_at < ? OR (responded_at = ? AND id < ?)", rsvp.RespondedAt, rsvp.RespondedAt, rsvp.ID).
+		Count(&ahead)
+	return ahead + 1
+}
@@ -0,0 +1,740 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Edit scopes for series occurrences
+const (
+	scopeThis      = "this"
+	scopeFollowing = "following"
+	scopeAll       = "all"
+)
+
+// GetSeriesList retrieves all event series
+func GetSeriesList(c *gin.Context) {
+	page, err := pagination.Paginate(c, database.DB.Model(&models.EventSeries{}), pagination.Key[models.EventSeries]{
+		Column:   "event_series.start_time",
+		Kind:     pagination.KindTime,
+		IDColumn: "event_series.id",
+		Desc:     true,
+		Value:    func(s models.EventSeries) interface{} { return s.StartTime },
+		ID:       func(s models.EventSeries) uint { return s.ID },
+	}, func(db *gorm.DB) *gorm.DB { return db.Preload("Organizer") })
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve series")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// CreateSeries creates a recurring event series and materializes its occurrences. Open-ended rules only get
+// their first year of occurrences.
+func CreateSeries(c *gin.Context) {
+	var input struct {
+		Name             string      `json:"name"`
+		Description      string      `json:"description"`
+		Location         string      `json:"location"`
+		RRule            string      `json:"rrule"`
+		StartTime        time.Time   `json:"start_time"`
+		DurationMinutes  int         `json:"duration_minutes"`
+		PointsAllocation int         `json:"points_allocation"`
+		ImageURL         string      `json:"image_url"`
+		Capacity         *int        `json:"capacity"`
+		ExceptionDates   []time.Time `json:"exception_dates"` // Occurrence start times to skip (EXDATE)
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Name == "" || input.StartTime.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and start_time are required"})
+		return
+	}
+	if input.DurationMinutes <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_minutes must be a positive integer"})
+		return
+	}
+	if msg := validateRSVPSettings(input.Capacity, nil, nil); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	rule, err := utils.ParseRRule(input.RRule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rrule: " + err.Error()})
+		return
+	}
+	if rule.Count > utils.MaxOccurrences {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rrule COUNT must be at most %d", utils.MaxOccurrences)})
+		return
+	}
+
+	series := models.EventSeries{
+		Name:             input.Name,
+		Description:      input.Description,
+		Location:         input.Location,
+		RRule:            rule.String(),
+		StartTime:        input.StartTime,
+		DurationMinutes:  input.DurationMinutes,
+		OrganizerID:      c.GetUint("user_id"),
+		PointsAllocation: input.PointsAllocation,
+		ImageURL:         input.ImageURL,
+		Capacity:         input.Capacity,
+	}
+	for _, date := range input.ExceptionDates {
+		series.Exceptions = append(series.Exceptions, models.EventSeriesException{OriginalStartTime: date})
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := tx.Create(&series).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
+		return
+	}
+
+	events, err := materializeSeries(tx, series)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series occurrences"})
+		return
+	}
+	if len(events) == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The rrule does not produce any occurrences"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	series.Events = events
+	c.JSON(http.StatusCreated, series)
+}
+
+// GetSeries retrieves a series with its exceptions and occurrences
+func GetSeries(c *gin.Context) {
+	seriesID := c.Param("seriesId")
+
+	var series models.EventSeries
+	err := database.DB.Preload("Organizer").Preload("Exceptions").
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("start_time asc") }).
+		First(&series, seriesID).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// UpdateSeriesOccurrence edits one occurrence, this and the following occurrences, or the whole series
+// The edited occurrence's ETag is needed as If-Match, like UpdateEvent, and archived occurrences are left as they are
+func UpdateSeriesOccurrence(c *gin.Context) {
+	seriesID := c.Param("seriesId")
+	eventID := c.Param("eventId")
+
+	var input struct {
+		Scope            string     `json:"scope"` // "this", "following" or "all"
+		Name             *string    `json:"name"`
+		Description      *string    `json:"description"`
+		Location         *string    `json:"location"`
+		PointsAllocation *int       `json:"points_allocation"`
+		ImageURL         *string    `json:"image_url"`
+		Capacity         *int       `json:"capacity"`
+		DurationMinutes  *int       `json:"duration_minutes"` // "following" and "all" only
+		StartTime        *time.Time `json:"start_time"`       // "this" only
+		EndTime          *time.Time `json:"end_time"`         // "this" only
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Scope != scopeThis && input.Scope != scopeFollowing && input.Scope != scopeAll {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be one of 'this', 'following' or 'all'"})
+		return
+	}
+	if input.Scope == scopeThis && input.DurationMinutes != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_minutes can only be changed for 'following' or 'all', use start_time and end_time instead"})
+		return
+	}
+	if input.Scope != scopeThis && (input.StartTime != nil || input.EndTime != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time and end_time can only be changed for a single occurrence"})
+		return
+	}
+	if input.DurationMinutes != nil && *input.DurationMinutes <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_minutes must be a positive integer"})
+		return
+	}
+	if msg := validateRSVPSettings(input.Capacity, nil, nil); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if !requireIfMatch(c) {
+		return
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	var series models.EventSeries
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&series, seriesID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if !canManageOrganizedBy(c, series.OrganizerID) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to update this series"})
+		return
+	}
+
+	var event models.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("series_id = ?", series.ID).
+		First(&event, eventID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found in this series"})
+		return
+	}
+
+	if input.Scope != scopeThis && event.OriginalStartTime == nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This occurrence is not part of the series rule, edit it with scope 'this'"})
+		return
+	}
+
+	// Editing "this and following" from the first occurrence is the same as editing the whole series
+	scope := input.Scope
+	if scope == scopeFollowing && !event.OriginalStartTime.After(series.StartTime) {
+		scope = scopeAll
+	}
+
+	var rule *utils.RRule
+	before := 0
+	if scope == scopeFollowing {
+		var err error
+		rule, err = utils.ParseRRule(series.RRule)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored rrule is invalid"})
+			return
+		}
+		if rule.Count > 0 {
+			for _, occurrence := range rule.Occurrences(series.StartTime) {
+				if occurrence.Before(*event.OriginalStartTime) {
+					before++
+				}
+			}
+			// No occurrence left before the split, a COUNT of 0 would leave the original rule unbounded
+			if before == 0 {
+				scope = scopeAll
+			}
+		}
+	}
+
+	if !checkEventEditable(c, event) {
+		tx.Rollback()
+		return
+	}
+
+	// Apply the changes to a single occurrence
+	applyToEvent := func(e *models.Event) {
+		if input.Name != nil {
+			e.Name = *input.Name
+		}
+		if input.Description != nil {
+			e.Description = *input.Description
+		}
+		if input.Location != nil {
+			e.Location = *input.Location
+		}
+		if input.PointsAllocation != nil {
+			e.PointsAllocation = *input.PointsAllocation
+		}
+		if input.ImageURL != nil {
+			e.ImageURL = *input.ImageURL
+		}
+		if input.Capacity != nil {
+			e.Capacity = input.Capacity
+		}
+		if input.DurationMinutes != nil && e.StartTime != nil {
+			end := e.StartTime.Add(time.Duration(*input.DurationMinutes) * time.Minute)
+			e.EndTime = &end
+		}
+	}
+
+	// Apply the changes to the defaults of a series
+	applyToSeries := func(s *models.EventSeries) {
+		if input.Name != nil {
+			s.Name = *input.Name
+		}
+		if input.Description != nil {
+			s.Description = *input.Description
+		}
+		if input.Location != nil {
+			s.Location = *input.Location
+		}
+		if input.PointsAllocation != nil {
+			s.PointsAllocation = *input.PointsAllocation
+		}
+		if input.ImageURL != nil {
+			s.ImageURL = *input.ImageURL
+		}
+		if input.Capacity != nil {
+			s.Capacity = input.Capacity
+		}
+		if input.DurationMinutes != nil {
+			s.DurationMinutes = *input.DurationMinutes
+		}
+	}
+
+	var updated []models.Event
+	switch scope {
+	case scopeThis:
+		previousPoints := event.PointsAllocation
+		applyToEvent(&event)
+		if input.StartTime != nil || input.EndTime != nil {
+			start, end := event.StartTime, event.EndTime
+			if input.StartTime != nil {
+				start = input.StartTime
+			}
+			if input.EndTime != nil {
+				end = input.EndTime
+			}
+			if start == nil || end == nil || !start.Before(*end) {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": "start_time must be before end_time"})
+				return
+			}
+			event.StartTime, event.EndTime = start, end
+		}
+		event.Detached = true
+		event.Sequence++
+		if err := tx.Omit(clause.Associations).Save(&event).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+			return
+		}
+		if _, err := services.RecalculateEventPoints(tx, event, previousPoints, c.GetUint("user_id")); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recalculate attendee points"})
+			return
+		}
+		updated = append(updated, event)
+
+	case scopeAll:
+		applyToSeries(&series)
+		if err := tx.Save(&series).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+			return
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("series_id = ? AND status <> ? AND (detached = ? OR id = ?)", series.ID, models.EventArchived, false, event.ID).
+			Find(&updated).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find series occurrences"})
+			return
+		}
+
+	case scopeFollowing:
+		// Split the series at this occurrence: the original rule ends before it and a new series takes over
+		split := *event.OriginalStartTime
+		newRule := *rule
+		if rule.Count > 0 {
+			rule.Count = before
+			newRule.Count -= before
+		} else {
+			until := split.Add(-time.Second)
+			rule.Until = &until
+		}
+
+		newSeries := series
+		newSeries.ID = 0
+		newSeries.CreatedAt = time.Time{}
+		newSeries.UpdatedAt = time.Time{}
+		newSeries.StartTime = split
+		newSeries.RRule = newRule.String()
+		applyToSeries(&newSeries)
+		if err := tx.Omit(clause.Associations).Create(&newSeries).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the following series"})
+			return
+		}
+
+		series.RRule = rule.String()
+		if err := tx.Omit(clause.Associations).Save(&series).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+			return
+		}
+
+		// Move the following occurrences and their exceptions to the new series
+		if err := tx.Model(&models.EventSeriesException{}).
+			Where("series_id = ? AND original_start_time >= ?", series.ID, split).
+			Update("series_id", newSeries.ID).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move series exceptions"})
+			return
+		}
+		if err := tx.Model(&models.Event{}).
+			Where("series_id = ? AND original_start_time >= ?", series.ID, split).
+			Update("series_id", newSeries.ID).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move series occurrences"})
+			return
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("series_id = ? AND status <> ? AND (detached = ? OR id = ?)", newSeries.ID, models.EventArchived, false, event.ID).
+			Find(&updated).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find series occurrences"})
+			return
+		}
+		series = newSeries
+	}
+
+	if scope != scopeThis {
+		for i := range updated {
+			previousPoints := updated[i].PointsAllocation
+			applyToEvent(&updated[i])
+			updated[i].Sequence++
+			if err := tx.Omit(clause.Associations).Save(&updated[i]).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series occurrences"})
+				return
+			}
+			// Past occurrences may already have attendees credited with the old points
+			if _, err := services.RecalculateEventPoints(tx, updated[i], previousPoints, c.GetUint("user_id")); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recalculate attendee points"})
+				return
+			}
+		}
+	}
+
+	// A raised (or removed) capacity may free spots for waitlisted users
+	promoted := make([][]models.RSVP, len(updated))
+	if input.Capacity != nil {
+		for i := range updated {
+			var err error
+			if promoted[i], err = promoteWaitlist(tx, updated[i]); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote waitlist"})
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	for i := range updated {
+		notifyPromoted(updated[i], promoted[i])
+	}
+
+	if scope == scopeThis {
+		c.Header("ETag", eventETag(event))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"series":         series,
+		"updated_events": updated,
+	})
+}
+
+// AddSeriesException removes a single occurrence from a series by recording an exception date
+func AddSeriesException(c *gin.Context) {
+	seriesID := c.Param("seriesId")
+
+	var input struct {
+		OriginalStartTime time.Time `json:"original_start_time"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	var series models.EventSeries
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&series, seriesID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if !canManageOrganizedBy(c, series.OrganizerID) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to update this series"})
+		return
+	}
+
+	rule, err := utils.ParseRRule(series.RRule)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored rrule is invalid"})
+		return
+	}
+	if !utils.ContainsTime(rule.Occurrences(series.StartTime), input.OriginalStartTime) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "original_start_time is not an occurrence of this series"})
+		return
+	}
+
+	// Occurrences that already have attendance can't be removed, that would silently drop points
+	var event models.Event
+	err = tx.Where("series_id = ? AND original_start_time = ?", series.ID, input.OriginalStartTime).First(&event).Error
+	if err == nil {
+		var attendances int64
+		if err := tx.Model(&models.Attendance{}).Where("event_id = ?", event.ID).Count(&attendances).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check attendances"})
+			return
+		}
+		if attendances > 0 {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "This occurrence already has attendances, delete the event instead"})
+			return
+		}
+
+		if err := tx.Where("event_id = ?", event.ID).Delete(&models.RSVP{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete RSVPs"})
+			return
+		}
+		// Nothing to restore without attendances, so the occurrence is deleted for good
+		if err := tx.Unscoped().Select("Awards").Delete(&event).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete occurrence"})
+			return
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find occurrence"})
+		return
+	}
+
+	exception := models.EventSeriesException{SeriesID: series.ID, OriginalStartTime: input.OriginalStartTime}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&exception).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exception"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, exception)
+}
+
+// DeleteSeriesException restores an excluded occurrence of a series
+func DeleteSeriesException(c *gin.Context) {
+	seriesID := c.Param("seriesId")
+	exceptionID := c.Param("exceptionId")
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	var series models.EventSeries
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&series, seriesID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if !canManageOrganizedBy(c, series.OrganizerID) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to update this series"})
+		return
+	}
+
+	result := tx.Where("series_id = ?", series.ID).Delete(&models.EventSeriesException{}, exceptionID)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exception"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exception not found"})
+		return
+	}
+
+	// Re-create the occurrence that is no longer excluded
+	events, err := materializeSeries(tx, series)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore occurrence"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Exception removed",
+		"restored_events": events,
+	})
+}
+
+// GetSeriesStats aggregates attendance over all occurrences of a series
+func GetSeriesStats(c *gin.Context) {
+	seriesID := c.Param("seriesId")
+
+	var series models.EventSeries
+	if err := database.DB.First(&series, seriesID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	var occurrences []struct {
+		EventID       uint       `json:"event_id"`
+		StartTime     *time.Time `json:"start_time"`
+		Attendance    int64      `json:"attendance"`
+		RSVPs         int64      `json:"rsvps"`
+		PointsAwarded int64      `json:"points_awarded"`
+	}
+	err := database.DB.Raw(`
+		SELECT e.id AS event_id, e.start_time,
+			(SELECT COUNT(*) FROM attendances a WHERE a.event_id = e.id) AS attendance,
+			(SELECT COUNT(*) FROM rsvps r WHERE r.event_id = e.id AND r.status = 'going') AS rsvps,
+			(SELECT COUNT(*) FROM attendances a WHERE a.event_id = e.id) * e.points_allocation AS points_awarded
+		FROM events e
+		WHERE e.series_id = ? AND e.deleted_at IS NULL
+		ORDER BY e.start_time ASC
+	`, series.ID).Scan(&occurrences).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute series statistics"})
+		return
+	}
+
+	var uniqueAttendees int64
+	if err := database.DB.Table("attendances").
+		Joins("JOIN events ON events.id = attendances.event_id").
+		Where("events.series_id = ? AND events.deleted_at IS NULL", series.ID).
+		Distinct("attendances.user_id").
+		Count(&uniqueAttendees).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unique attendees"})
+		return
+	}
+
+	// Averages only consider occurrences that have already started
+	now := time.Now()
+	var totalAttendance, totalPoints, pastOccurrences int64
+	for _, o := range occurrences {
+		totalAttendance += o.Attendance
+		totalPoints += o.PointsAwarded
+		if o.StartTime != nil && o.StartTime.Before(now) {
+			pastOccurrences++
+		}
+	}
+	var averageAttendance float64
+	if pastOccurrences > 0 {
+		averageAttendance = float64(totalAttendance) / float64(pastOccurrences)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series_id":          series.ID,
+		"occurrence_count":   len(occurrences),
+		"past_occurrences":   pastOccurrences,
+		"total_attendance":   totalAttendance,
+		"unique_attendees":   uniqueAttendees,
+		"average_attendance": averageAttendance,
+		"points_awarded":     totalPoints,
+		"occurrences":        occurrences,
+	})
+}
+
+// Helper function to create the missing occurrences of a series, skipping exception dates
+func materializeSeries(tx *gorm.DB, series models.EventSeries) ([]models.Event, error) {
+	rule, err := utils.ParseRRule(series.RRule)
+	if err != nil {
+		return nil, err
+	}
+
+	var exceptions []models.EventSeriesException
+	if err := tx.Where("series_id = ?", series.ID).Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+	var existing []models.Event
+	if err := tx.Where("series_id = ?", series.ID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	skip := make([]time.Time, 0, len(exceptions)+len(existing))
+	for _, exception := range exceptions {
+		skip = append(skip, exception.OriginalStartTime)
+	}
+	for _, event := range existing {
+		if event.OriginalStartTime != nil {
+			skip = append(skip, *event.OriginalStartTime)
+		}
+	}
+
+	duration := time.Duration(series.DurationMinutes) * time.Minute
+	var events []models.Event
+	for _, occurrence := range rule.Occurrences(series.StartTime) {
+		if utils.ContainsTime(skip, occurrence) {
+			continue
+		}
+
+		start := occurrence
+		end := occurrence.Add(duration)
+		events = append(events, models.Event{
+			Name:              series.Name,
+			Description:       series.Description,
+			Location:          series.Location,
+			StartTime:         &start,
+			EndTime:           &end,
+			OrganizerID:       series.OrganizerID,
+			PointsAllocation:  series.PointsAllocation,
+			ImageURL:          series.ImageURL,
+			Status:            string(models.EventPublished),
+			Capacity:          series.Capacity,
+			SeriesID:          &series.ID,
+			OriginalStartTime: &start,
+		})
+	}
+
+	if len(events) > 0 {
+		if err := tx.CreateInBatches(events, 100).Error; err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
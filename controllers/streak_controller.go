@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/rules"
+	"gorm.io/gorm"
+)
+
+// streakAward is an award for a weekly streak and whether the user earned it
+type streakAward struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	IconURL string `json:"icon_url"`
+	Weeks   int    `json:"weeks"`
+	Earned  bool   `json:"earned"`
+}
+
+// GetUserStreak retrieves a user's current and longest streak of consecutive weeks with an attendance, along
+// with the streak awards they earned or can work towards
+func GetUserStreak(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Users who never attended anything have no streak recorded
+	streak := models.UserStreak{UserID: user.ID}
+	if err := database.DB.First(&streak, user.ID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve streak"})
+		return
+	}
+
+	awards, err := streakAwards(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve streak awards"})
+		return
+	}
+
+	now := time.Now()
+	current := streak.Current(now)
+	var currentStart *time.Time
+	if current > 0 {
+		currentStart = streak.CurrentStart
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"current_weeks":      current,
+		"current_start":      currentStart,
+		"attended_this_week": streak.LastWeek != nil && !streak.LastWeek.Before(rules.WeekStart(now)),
+		"last_week":          streak.LastWeek,
+		"longest_weeks":      streak.LongestWeeks,
+		"longest_start":      streak.LongestStart,
+		"awards":             awards,
+	})
+}
+
+// Helper function to list the awards for a weekly streak, shortest streak first, and whether a user earned them
+func streakAwards(db *gorm.DB, userID uint) ([]streakAward, error) {
+	var awards []models.Award
+	if err := db.Where("criteria->>'type' = ?", rules.RuleWeeklyStreak).Find(&awards).Error; err != nil {
+		return nil, err
+	}
+	var earnedIDs []uint
+	if err := db.Model(&models.UserBadge{}).Where("user_id = ?", userID).Pluck("award_id", &earnedIDs).Error; err != nil {
+		return nil, err
+	}
+	earned := make(map[uint]bool, len(earnedIDs))
+	for _, id := range earnedIDs {
+		earned[id] = true
+	}
+
+	result := make([]streakAward, 0, len(awards))
+	for _, award := range awards {
+		result = append(result, streakAward{
+			ID:      award.ID,
+			Name:    award.Name,
+			IconURL: award.IconURL,
+			Weeks:   award.Criteria.Weeks,
+			Earned:  earned[award.ID],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Weeks < result[j].Weeks })
+	return result, nil
+}
@@ -0,0 +1,613 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Free text answers longer than this are refused
+const surveyTextMaxLength = 2000
+
+// surveyQuestionInput is a question as sent when creating or replacing a survey's questions
+type surveyQuestionInput struct {
+	Type          string   `json:"type"`
+	Prompt        string   `json:"prompt"`
+	Options       []string `json:"options"`
+	AllowMultiple bool     `json:"allow_multiple"`
+	Required      bool     `json:"required"`
+}
+
+// surveyQuestionResult aggregates the answers to one question
+type surveyQuestionResult struct {
+	QuestionID    uint           `json:"question_id"`
+	Prompt        string         `json:"prompt"`
+	Type          string         `json:"type"`
+	Answered      int            `json:"answered"`
+	AverageRating *float64       `json:"average_rating,omitempty"`
+	Distribution  map[string]int `json:"distribution,omitempty"` // Count per rating or option
+	Texts         []string       `json:"texts,omitempty"`
+}
+
+// GetEventSurvey returns the survey of an event and whether the current user can still answer it
+func GetEventSurvey(c *gin.Context) {
+	event, ok := surveyEvent(c)
+	if !ok {
+		return
+	}
+	survey, ok := findSurvey(c, database.DB, event.ID)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var responded, attended int64
+	database.DB.Model(&models.SurveyResponse{}).Where("survey_id = ? AND user_id = ?", survey.ID, userID).Count(&responded)
+	database.DB.Model(&models.Attendance{}).Where("event_id = ? AND user_id = ?", event.ID, userID).Count(&attended)
+
+	c.JSON(http.StatusOK, gin.H{
+		"survey":        survey,
+		"has_responded": responded > 0,
+		"can_respond":   responded == 0 && attended > 0 && !survey.IsClosed(),
+	})
+}
+
+// CreateEventSurvey attaches a feedback survey to an event (its organizer, staff or admins)
+func CreateEventSurvey(c *gin.Context) {
+	var input struct {
+		Title       string                `json:"title" binding:"required"`
+		Description string                `json:"description"`
+		BonusPoints int                   `json:"bonus_points"`
+		ClosesAt    *time.Time            `json:"closes_at"`
+		Questions   []surveyQuestionInput `json:"questions"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.BonusPoints < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bonus_points must not be negative"})
+		return
+	}
+	questions, msg := buildSurveyQuestions(input.Questions)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	event, ok := surveyEvent(c)
+	if !ok {
+		return
+	}
+	if !canManageEvent(c, event) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this event's survey"})
+		return
+	}
+
+	survey := models.Survey{
+		EventID:     event.ID,
+		Title:       strings.TrimSpace(input.Title),
+		Description: input.Description,
+		BonusPoints: input.BonusPoints,
+		ClosesAt:    input.ClosesAt,
+		Questions:   questions,
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// The unique index on event_id turns a second survey into a no-op
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Questions").Create(&survey)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errSurveyExists
+		}
+		for i := range survey.Questions {
+			survey.Questions[i].SurveyID = survey.ID
+		}
+		return tx.Create(&survey.Questions).Error
+	})
+	if errors.Is(err, errSurveyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create survey"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, survey)
+}
+
+// UpdateEventSurvey partially updates the survey of an event, its questions and bonus can only
+// change until the first response (its organizer, staff or admins)
+func UpdateEventSurvey(c *gin.Context) {
+	var input struct {
+		Title       *string                   `json:"title"`
+		Description *string                   `json:"description"`
+		BonusPoints *int                      `json:"bonus_points"`
+		ClosesAt    utils.Nullable[time.Time] `json:"closes_at"` // null keeps the survey open indefinitely
+		Questions   *[]surveyQuestionInput    `json:"questions"` // Replaces every question
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.BonusPoints != nil && *input.BonusPoints < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bonus_points must not be negative"})
+		return
+	}
+	var questions []models.SurveyQuestion
+	if input.Questions != nil {
+		var msg string
+		if questions, msg = buildSurveyQuestions(*input.Questions); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	event, ok := surveyEvent(c)
+	if !ok {
+		return
+	}
+	if !canManageEvent(c, event) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this event's survey"})
+		return
+	}
+
+	tx := database.DB.Begin()
+	survey, ok := findSurvey(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}), event.ID)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	if input.Questions != nil || (input.BonusPoints != nil && *input.BonusPoints != survey.BonusPoints) {
+		var responses int64
+		if err := tx.Model(&models.SurveyResponse{}).Where("survey_id = ?", survey.ID).Count(&responses).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check survey responses"})
+			return
+		}
+		if responses > 0 {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "Questions and bonus points can't change once attendees responded"})
+			return
+		}
+	}
+
+	if input.Title != nil {
+		survey.Title = strings.TrimSpace(*input.Title)
+		if survey.Title == "" {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "title must not be empty"})
+			return
+		}
+	}
+	if input.Description != nil {
+		survey.Description = *input.Description
+	}
+	if input.BonusPoints != nil {
+		survey.BonusPoints = *input.BonusPoints
+	}
+	survey.ClosesAt = input.ClosesAt.Or(survey.ClosesAt)
+
+	if err := tx.Omit(clause.Associations).Save(&survey).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update survey"})
+		return
+	}
+	if input.Questions != nil {
+		if err := tx.Where("survey_id = ?", survey.ID).Delete(&models.SurveyQuestion{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace survey questions"})
+			return
+		}
+		for i := range questions {
+			questions[i].SurveyID = survey.ID
+		}
+		if err := tx.Create(&questions).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace survey questions"})
+			return
+		}
+		survey.Questions = questions
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, survey)
+}
+
+// DeleteEventSurvey removes the survey of an event as long as nobody responded (its organizer, staff or admins)
+func DeleteEventSurvey(c *gin.Context) {
+	event, ok := surveyEvent(c)
+	if !ok {
+		return
+	}
+	if !canManageEvent(c, event) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this event's survey"})
+		return
+	}
+
+	tx := database.DB.Begin()
+	survey, ok := findSurvey(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}), event.ID)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	var responses int64
+	if err := tx.Model(&models.SurveyResponse{}).Where("survey_id = ?", survey.ID).Count(&responses).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check survey responses"})
+		return
+	}
+	if responses > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Surveys can't be deleted once attendees responded, close it instead"})
+		return
+	}
+
+	if err := tx.Where("survey_id = ?", survey.ID).Delete(&models.SurveyQuestion{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete survey questions"})
+		return
+	}
+	if err := tx.Delete(&survey).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete survey"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Survey deleted"})
+}
+
+// SubmitSurveyResponse records the current user's answers to the survey of an event they attended,
+// crediting the survey's bonus points. Every attendee can respond once.
+func SubmitSurveyResponse(c *gin.Context) {
+	var input struct {
+		Answers []struct {
+			QuestionID uint     `json:"question_id"`
+			Rating     *int     `json:"rating"`
+			Choices    []string `json:"choices"`
+			Text       string   `json:"text"`
+		} `json:"answers"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, ok := surveyEvent(c)
+	if !ok {
+		return
+	}
+	survey, ok := findSurvey(c, database.DB, event.ID)
+	if !ok {
+		return
+	}
+	if survey.IsClosed() {
+		c.JSON(http.StatusConflict, gin.H{"error": "This survey is closed"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var attended int64
+	if err := database.DB.Model(&models.Attendance{}).Where("event_id = ? AND user_id = ?", event.ID, userID).
+		Count(&attended).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check attendance"})
+		return
+	}
+	if attended == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only attendees of the event can respond to its survey"})
+		return
+	}
+
+	// Check every answer against its question
+	questions := make(map[uint]models.SurveyQuestion, len(survey.Questions))
+	for _, question := range survey.Questions {
+		questions[question.ID] = question
+	}
+	answers := make([]models.SurveyAnswer, 0, len(input.Answers))
+	answered := make(map[uint]bool)
+	for _, in := range input.Answers {
+		question, ok := questions[in.QuestionID]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Question %d is not part of this survey", in.QuestionID)})
+			return
+		}
+		if answered[question.ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Question %d is answered more than once", question.ID)})
+			return
+		}
+
+		answer := models.SurveyAnswer{QuestionID: question.ID}
+		switch models.QuestionType(question.Type) {
+		case models.QuestionRating:
+			if in.Rating == nil {
+				continue
+			}
+			if *in.Rating < 1 || *in.Rating > models.SurveyRatingMax {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Ratings must be between 1 and %d", models.SurveyRatingMax)})
+				return
+			}
+			answer.Rating = in.Rating
+		case models.QuestionChoice:
+			if len(in.Choices) == 0 {
+				continue
+			}
+			if len(in.Choices) > 1 && !question.AllowMultiple {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Question %d only allows one choice", question.ID)})
+				return
+			}
+			for _, choice := range in.Choices {
+				if !containsString(question.Options, choice) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%q is not an option of question %d", choice, question.ID)})
+					return
+				}
+			}
+			answer.Choices = in.Choices
+		case models.QuestionText:
+			text := strings.TrimSpace(in.Text)
+			if text == "" {
+				continue
+			}
+			if len(text) > surveyTextMaxLength {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Answers are limited to %d characters", surveyTextMaxLength)})
+				return
+			}
+			answer.Text = text
+		}
+		answered[question.ID] = true
+		answers = append(answers, answer)
+	}
+	for _, question := range survey.Questions {
+		if question.Required && !answered[question.ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Question %d is required", question.ID)})
+			return
+		}
+	}
+
+	var granted int64
+	response := models.SurveyResponse{SurveyID: survey.ID, UserID: userID}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// The unique index makes a second response a no-op, even when both arrive at once
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&response)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAlreadyResponded
+		}
+
+		for i := range answers {
+			answers[i].ResponseID = response.ID
+		}
+		if len(answers) > 0 {
+			if err := tx.Create(&answers).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		note := fmt.Sprintf("Completed the survey of %q", event.Name)
+		granted, err = services.CreditUserPoints(tx, userID, survey.BonusPoints, models.PointReasonSurveyCompleted, &event.ID, note)
+		return err
+	})
+	if errors.Is(err, errAlreadyResponded) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record response"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":            "Thank you for your feedback",
+		"points_added":       survey.BonusPoints,
+		"new_awards_granted": granted,
+	})
+}
+
+// GetSurveyResults aggregates the responses to the survey of an event, free text answers are
+// listed without who wrote them (its organizer, staff or admins)
+func GetSurveyResults(c *gin.Context) {
+	event, ok := surveyEvent(c)
+	if !ok {
+		return
+	}
+	if !canManageEvent(c, event) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view this survey's results"})
+		return
+	}
+	survey, ok := findSurvey(c, database.DB, event.ID)
+	if !ok {
+		return
+	}
+
+	var responseCount, attendeeCount int64
+	if err := database.DB.Model(&models.SurveyResponse{}).Where("survey_id = ?", survey.ID).Count(&responseCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count responses"})
+		return
+	}
+	if err := database.DB.Model(&models.Attendance{}).Where("event_id = ?", event.ID).
+		Distinct("user_id").Count(&attendeeCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count attendees"})
+		return
+	}
+
+	var answers []models.SurveyAnswer
+	if err := database.DB.Joins("JOIN survey_responses ON survey_responses.id = survey_answers.response_id").
+		Where("survey_responses.survey_id = ?", survey.ID).
+		Order("survey_answers.id").
+		Find(&answers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve answers"})
+		return
+	}
+
+	results := make([]*surveyQuestionResult, 0, len(survey.Questions))
+	byQuestion := make(map[uint]*surveyQuestionResult, len(survey.Questions))
+	ratingSums := make(map[uint]int)
+	for _, question := range survey.Questions {
+		result := &surveyQuestionResult{QuestionID: question.ID, Prompt: question.Prompt, Type: question.Type}
+		switch models.QuestionType(question.Type) {
+		case models.QuestionRating:
+			result.Distribution = make(map[string]int)
+			for rating := 1; rating <= models.SurveyRatingMax; rating++ {
+				result.Distribution[strconv.Itoa(rating)] = 0
+			}
+		case models.QuestionChoice:
+			result.Distribution = make(map[string]int)
+			for _, option := range question.Options {
+				result.Distribution[option] = 0
+			}
+		case models.QuestionText:
+			result.Texts = []string{}
+		}
+		results = append(results, result)
+		byQuestion[question.ID] = result
+	}
+
+	for _, answer := range answers {
+		result, ok := byQuestion[answer.QuestionID]
+		if !ok {
+			continue
+		}
+		result.Answered++
+		switch {
+		case answer.Rating != nil:
+			result.Distribution[strconv.Itoa(*answer.Rating)]++
+			ratingSums[answer.QuestionID] += *answer.Rating
+		case len(answer.Choices) > 0:
+			for _, choice := range answer.Choices {
+				result.Distribution[choice]++
+			}
+		default:
+			result.Texts = append(result.Texts, answer.Text)
+		}
+	}
+	for id, sum := range ratingSums {
+		average := float64(sum) / float64(byQuestion[id].Answered)
+		byQuestion[id].AverageRating = &average
+	}
+
+	var responseRate float64
+	if attendeeCount > 0 {
+		responseRate = float64(responseCount) / float64(attendeeCount)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"survey_id":      survey.ID,
+		"title":          survey.Title,
+		"response_count": responseCount,
+		"attendee_count": attendeeCount,
+		"response_rate":  responseRate,
+		"questions":      results,
+	})
+}
+
+var (
+	errSurveyExists     = errors.New("this event already has a survey")
+	errAlreadyResponded = errors.New("you already responded to this survey")
+)
+
+// Helper function to load the event of a survey route, drafts are only visible to the people who can manage them
+func surveyEvent(c *gin.Context) (models.Event, bool) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("eventId")).Error; err != nil ||
+		(event.Status == string(models.EventDraft) && !canManageEvent(c, event)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return event, false
+	}
+	return event, true
+}
+
+// Helper function to load the survey of an event with its questions, responds with 404 when there is none
+func findSurvey(c *gin.Context, db *gorm.DB, eventID uint) (models.Survey, bool) {
+	var survey models.Survey
+	err := db.Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("event_id = ?", eventID).First(&survey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This event has no survey"})
+		return survey, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve survey"})
+		return survey, false
+	}
+	return survey, true
+}
+
+// Helper function to validate survey questions, returns an error message or ""
+func buildSurveyQuestions(inputs []surveyQuestionInput) ([]models.SurveyQuestion, string) {
+	if len(inputs) == 0 {
+		return nil, "A survey needs at least one question"
+	}
+
+	questions := make([]models.SurveyQuestion, 0, len(inputs))
+	for i, in := range inputs {
+		question := models.SurveyQuestion{
+			Position: i + 1,
+			Type:     in.Type,
+			Prompt:   strings.TrimSpace(in.Prompt),
+			Required: in.Required,
+		}
+		if question.Prompt == "" {
+			return nil, fmt.Sprintf("Question %d needs a prompt", i+1)
+		}
+
+		switch models.QuestionType(in.Type) {
+		case models.QuestionRating, models.QuestionText:
+			if len(in.Options) > 0 || in.AllowMultiple {
+				return nil, fmt.Sprintf("Question %d: options are only allowed on choice questions", i+1)
+			}
+		case models.QuestionChoice:
+			seen := make(map[string]bool)
+			for _, option := range in.Options {
+				option = strings.TrimSpace(option)
+				if option == "" || seen[option] {
+					return nil, fmt.Sprintf("Question %d: options must be unique and not empty", i+1)
+				}
+				seen[option] = true
+				question.Options = append(question.Options, option)
+			}
+			if len(question.Options) < 2 {
+				return nil, fmt.Sprintf("Question %d: choice questions need at least two options", i+1)
+			}
+			question.AllowMultiple = in.AllowMultiple
+		default:
+			return nil, fmt.Sprintf("Question %d: type must be one of 'rating', 'choice' or 'text'", i+1)
+		}
+		questions = append(questions, question)
+	}
+	return questions, ""
+}
+
+// Helper function to check whether a list contains a string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
+	"gorm.io/gorm"
+)
+
+// teamMember is a current membership along with who it belongs to
+type teamMember struct {
+	models.TeamMembership
+	Name              string `json:"name"`
+	PhotoThumbnailURL string `json:"photo_thumbnail_url"`
+}
+
+// GetTeams lists teams by name, optionally of one kind (house or club)
+func GetTeams(c *gin.Context) {
+	query := database.DB.Model(&models.Team{})
+	if kind := c.Query("kind"); kind != "" {
+		if !validTeamKind(kind) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be house or club"})
+			return
+		}
+		query = query.Where("kind = ?", kind)
+	}
+
+	page, err := pagination.Paginate(c, query, pagination.Key[models.Team]{
+		Column:   "name",
+		Kind:     pagination.KindString,
+		IDColumn: "id",
+		Value:    func(t models.Team) interface{} { return t.Name },
+		ID:       func(t models.Team) uint { return t.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve teams")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetTeamLeaderboard ranks teams by the points their members earned while they were members, optionally of
+// one kind and within a time range (from/to, RFC3339). Teams with the same points share a rank.
+func GetTeamLeaderboard(c *gin.Context) {
+	queryParams := struct {
+		Kind string `form:"kind"`
+		From string `form:"from"`
+		To   string `form:"to"`
+	}{}
+	if err := c.ShouldBindQuery(&queryParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	filter := services.TeamScoreFilter{Kind: queryParams.Kind}
+	if filter.Kind != "" && !validTeamKind(filter.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be house or club"})
+		return
+	}
+	if queryParams.From != "" {
+		from, err := time.Parse(time.RFC3339, queryParams.From)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from format. Use RFC3339 (e.g., 2023-10-01T00:00:00Z)"})
+			return
+		}
+		filter.From = &from
+	}
+	if queryParams.To != "" {
+		to, err := time.Parse(time.RFC3339, queryParams.To)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to format. Use RFC3339 (e.g., 2023-10-01T00:00:00Z)"})
+			return
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	scores, err := services.TeamScores(database.DB, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute team leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": scores})
+}
+
+// GetTeam retrieves a single team along with its all-time points
+func GetTeam(c *gin.Context) {
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("teamId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	scores, err := services.TeamScores(database.DB, services.TeamScoreFilter{TeamIDs: []uint{team.ID}})
+	if err != nil || len(scores) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute team points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"team":    team,
+		"members": scores[0].Members,
+		"points":  scores[0].Points,
+	})
+}
+
+// CreateTeam adds a house or a club (requires admin permission)
+func CreateTeam(c *gin.Context) {
+	var input struct {
+		Name        string `json:"name" binding:"required"`
+		Kind        string `json:"kind" binding:"required"`
+		Description string `json:"description"`
+		ImageURL    string `json:"image_url"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team := models.Team{
+		Name:        strings.TrimSpace(input.Name),
+		Kind:        input.Kind,
+		Description: input.Description,
+		ImageURL:    input.ImageURL,
+	}
+	if team.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+		return
+	}
+	if !validTeamKind(team.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be house or club"})
+		return
+	}
+
+	if err := database.DB.Create(&team).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A team with this name already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, team)
+}
+
+// UpdateTeam partially updates a team, its kind can't change once it has members (requires admin permission)
+func UpdateTeam(c *gin.Context) {
+	var input struct {
+		Name        *string `json:"name"`
+		Kind        *string `json:"kind"`
+		Description *string `json:"description"`
+		ImageURL    *string `json:"image_url"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("teamId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	if input.Name != nil {
+		team.Name = strings.TrimSpace(*input.Name)
+		if team.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+			return
+		}
+	}
+	if input.Kind != nil && *input.Kind != team.Kind {
+		if !validTeamKind(*input.Kind) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be house or club"})
+			return
+		}
+		// Members of a club could be in several of them, which a house doesn't allow
+		var members int64
+		if err := database.DB.Model(&models.TeamMembership{}).Where("team_id = ?", team.ID).
+			Count(&members).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+			return
+		}
+		if members > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The kind of a team can't change once it has members"})
+			return
+		}
+		team.Kind = *input.Kind
+	}
+	if input.Description != nil {
+		team.Description = *input.Description
+	}
+	if input.ImageURL != nil {
+		team.ImageURL = *input.ImageURL
+	}
+
+	if err := database.DB.Save(&team).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A team with this name already exists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, team)
+}
+
+// DeleteTeam removes a team along with its memberships and its entries in competitions it didn't win
+// (requires admin permission)
+func DeleteTeam(c *gin.Context) {
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("teamId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	var won int64
+	if err := database.DB.Model(&models.Competition{}).Where("winner_team_id = ?", team.ID).Count(&won).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+	if won > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Teams that won a competition can't be deleted"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamMembership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM competition_teams WHERE team_id = ?", team.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&team).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted"})
+}
+
+// GetTeamMembers lists the current members of a team, earliest to join first
+func GetTeamMembers(c *gin.Context) {
+	query := database.DB.Model(&models.TeamMembership{}).
+		Select("team_memberships.*, users.name, users.photo_thumbnail_url").
+		Joins("JOIN users ON users.id = team_memberships.user_id AND users.deleted_at IS NULL").
+		Where("team_memberships.team_id = ? AND team_memberships.left_at IS NULL", c.Param("teamId"))
+
+	page, err := pagination.Paginate(c, query, pagination.Key[teamMember]{
+		Column:   "team_memberships.joined_at",
+		Kind:     pagination.KindTime,
+		IDColumn: "team_memberships.id",
+		Value:    func(m teamMember) interface{} { return m.JoinedAt },
+		ID:       func(m teamMember) uint { return m.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve team members")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetUserTeams lists the teams a user currently belongs to
+func GetUserTeams(c *gin.Context) {
+	var teams []models.Team
+	if err := database.DB.
+		Joins("JOIN team_memberships ON team_memberships.team_id = teams.id AND team_memberships.left_at IS NULL").
+		Where("team_memberships.user_id = ?", c.Param("id")).
+		Order("teams.kind, teams.name").
+		Find(&teams).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve teams"})
+		return
+	}
+
+	c.JSON(http.StatusOK, teams)
+}
+
+// AddTeamMember adds a user to a team. Users join clubs themselves, admins assign houses and can add anyone
+// by passing user_id.
+func AddTeamMember(c *gin.Context) {
+	var input struct {
+		UserID uint `json:"user_id"` // Defaults to the current user
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("teamId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	isAdmin := c.GetString("role") == string(models.RoleAdmin)
+	userID := c.GetUint("user_id")
+	if input.UserID != 0 && input.UserID != userID {
+		if !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add other users to a team"})
+			return
+		}
+		userID = input.UserID
+	}
+	if models.TeamKind(team.Kind).Exclusive() && !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Houses are assigned by admins"})
+		return
+	}
+
+	membership, err := services.JoinTeam(database.DB, team.ID, userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case errors.Is(err, services.ErrAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": "The user is already a member of this team"})
+		return
+	case errors.Is(err, services.ErrExclusiveTeam):
+		c.JSON(http.StatusConflict, gin.H{"error": "The user already belongs to a " + team.Kind + ", they have to leave it first"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join team"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, membership)
+}
+
+// RemoveTeamMember ends a user's membership of a team, users can leave clubs themselves and admins can remove
+// anyone. The team keeps the points earned while the user was a member.
+func RemoveTeamMember(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("teamId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	isAdmin := c.GetString("role") == string(models.RoleAdmin)
+	if !isAdmin && (uint(userID) != c.GetUint("user_id") || models.TeamKind(team.Kind).Exclusive()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to remove this member"})
+		return
+	}
+
+	if err := services.LeaveTeam(database.DB, team.ID, uint(userID)); err != nil {
+		if errors.Is(err, services.ErrNotMember) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The user is not a member of this team"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left team"})
+}
+
+// Helper function to check a team kind
+func validTeamKind(kind string) bool {
+	return kind == string(models.TeamHouse) || kind == string(models.TeamClub)
+}
@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetTemplates lists every event template by name
+func GetTemplates(c *gin.Context) {
+	page, err := pagination.Paginate(c, database.DB.Model(&models.EventTemplate{}), pagination.Key[models.EventTemplate]{
+		Column:   "event_templates.name",
+		Kind:     pagination.KindString,
+		IDColumn: "event_templates.id",
+		Value:    func(t models.EventTemplate) interface{} { return t.Name },
+		ID:       func(t models.EventTemplate) uint { return t.ID },
+	}, preloadTemplateRelations)
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetTemplate retrieves a single event template
+func GetTemplate(c *gin.Context) {
+	templateID := c.Param("templateId")
+	var template models.EventTemplate
+	if err := database.DB.Scopes(preloadTemplateRelations).First(&template, templateID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// CreateTemplate creates a named event template
+func CreateTemplate(c *gin.Context) {
+	var input struct {
+		Name             string `json:"name" binding:"required"`
+		Description      string `json:"description"`
+		Location         string `json:"location"`
+		PointsAllocation int    `json:"points_allocation"`
+		ImageURL         string `json:"image_url"`
+		CategoryID       *uint  `json:"category_id"`
+		Capacity         *int   `json:"capacity"`
+		AwardIDs         []uint `json:"award_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateRSVPSettings(input.Capacity, nil, nil); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	template := models.EventTemplate{
+		Name:             strings.TrimSpace(input.Name),
+		Description:      input.Description,
+		Location:         input.Location,
+		PointsAllocation: input.PointsAllocation,
+		ImageURL:         input.ImageURL,
+		CategoryID:       input.CategoryID,
+		Capacity:         input.Capacity,
+		CreatedByID:      c.GetUint("user_id"),
+	}
+	if template.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkTemplateReferences(tx, input.CategoryID, input.AwardIDs, &template.Awards); err != nil {
+			return err
+		}
+		if err := checkTemplateName(tx, template.Name, 0); err != nil {
+			return err
+		}
+		return tx.Omit("Awards.*").Create(&template).Error
+	})
+	if !templateError(c, err, "Failed to create template") {
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// UpdateTemplate partially updates an event template (its creator, staff or admins)
+func UpdateTemplate(c *gin.Context) {
+	templateID := c.Param("templateId")
+	var input struct {
+		Name             *string `json:"name"`
+		Description      *string `json:"description"`
+		Location         *string `json:"location"`
+		PointsAllocation *int    `json:"points_allocation"`
+		ImageURL         *string `json:"image_url"`
+		CategoryID       *uint   `json:"category_id"`
+		Capacity         *int    `json:"capacity"`
+		AwardIDs         *[]uint `json:"award_ids"` // Replaces the awards when present, an empty list clears them
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateRSVPSettings(input.Capacity, nil, nil); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var template models.EventTemplate
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&template, templateID).Error; err != nil {
+			return err
+		}
+		if !canManageOrganizedBy(c, template.CreatedByID) {
+			return errTemplateForbidden
+		}
+
+		var awardIDs []uint
+		if input.AwardIDs != nil {
+			awardIDs = *input.AwardIDs
+		}
+		var awards []models.Award
+		if err := checkTemplateReferences(tx, input.CategoryID, awardIDs, &awards); err != nil {
+			return err
+		}
+
+		if input.Name != nil {
+			template.Name = strings.TrimSpace(*input.Name)
+			if template.Name == "" {
+				return errTemplateName
+			}
+			if err := checkTemplateName(tx, template.Name, template.ID); err != nil {
+				return err
+			}
+		}
+		if input.Description != nil {
+			template.Description = *input.Description
+		}
+		if input.Location != nil {
+			template.Location = *input.Location
+		}
+		if input.PointsAllocation != nil {
+			template.PointsAllocation = *input.PointsAllocation
+		}
+		if input.ImageURL != nil {
+			template.ImageURL = *input.ImageURL
+		}
+		if input.CategoryID != nil {
+			template.CategoryID = input.CategoryID
+		}
+		if input.Capacity != nil {
+			template.Capacity = input.Capacity
+		}
+
+		if err := tx.Omit(clause.Associations).Save(&template).Error; err != nil {
+			return err
+		}
+		if input.AwardIDs != nil {
+			return tx.Model(&template).Association("Awards").Replace(awards)
+		}
+		return nil
+	})
+	if !templateError(c, err, "Failed to update template") {
+		return
+	}
+
+	database.DB.Scopes(preloadTemplateRelations).First(&template, template.ID)
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate removes an event template, events created from it are not affected (its creator, staff or admins)
+func DeleteTemplate(c *gin.Context) {
+	templateID := c.Param("templateId")
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var template models.EventTemplate
+		if err := tx.First(&template, templateID).Error; err != nil {
+			return err
+		}
+		if !canManageOrganizedBy(c, template.CreatedByID) {
+			return errTemplateForbidden
+		}
+		return tx.Select("Awards").Delete(&template).Error
+	})
+	if !templateError(c, err, "Failed to delete template") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted"})
+}
+
+var (
+	errTemplateForbidden = errors.New("you do not have permission to modify this template")
+	errTemplateName      = errors.New("name must not be empty")
+	errTemplateCategory  = errors.New("invalid category ID")
+	errTemplateAwardIDs  = errors.New("some award IDs not found")
+	errTemplateNameTaken = errors.New("a template with this name already exists")
+)
+
+// Helper function to preload the relations returned with templates
+func preloadTemplateRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("CreatedBy").Preload("Category").Preload("Awards")
+}
+
+// Helper function to check the category and awards a template refers to, the awards are loaded into awards
+func checkTemplateReferences(tx *gorm.DB, categoryID *uint, awardIDs []uint, awards *[]models.Award) error {
+	if categoryID != nil {
+		if err := tx.First(&models.Category{}, *categoryID).Error; err != nil {
+			return errTemplateCategory
+		}
+	}
+	if len(awardIDs) > 0 {
+		if err := tx.Where("id IN ?", awardIDs).Find(awards).Error; err != nil {
+			return err
+		}
+		if len(*awards) != len(awardIDs) {
+			return errTemplateAwardIDs
+		}
+	}
+	return nil
+}
+
+// Helper function to make sure no other template uses a name
+func checkTemplateName(tx *gorm.DB, name string, templateID uint) error {
+	var count int64
+	if err := tx.Model(&models.EventTemplate{}).Where("name = ? AND id <> ?", name, templateID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errTemplateNameTaken
+	}
+	return nil
+}
+
+// Helper function to respond to a failed template change, returns true when there was no error
+func templateError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+	case errors.Is(err, errTemplateForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, errTemplateName), errors.Is(err, errTemplateCategory), errors.Is(err, errTemplateAwardIDs):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errTemplateNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
+	"gorm.io/gorm"
+)
+
+// termStanding is a final standing along with who it belongs to
+type termStanding struct {
+	models.TermStanding
+	Name              string `json:"name"`
+	PhotoThumbnailURL string `json:"photo_thumbnail_url"`
+}
+
+// GetTerms lists every term, most recent first
+func GetTerms(c *gin.Context) {
+	page, err := pagination.Paginate(c, database.DB.Model(&models.Term{}), pagination.Key[models.Term]{
+		Column:   "starts_at",
+		Kind:     pagination.KindTime,
+		IDColumn: "id",
+		Desc:     true,
+		Value:    func(t models.Term) interface{} { return t.StartsAt },
+		ID:       func(t models.Term) uint { return t.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve terms")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetCurrentTerm retrieves the term in progress
+func GetCurrentTerm(c *gin.Context) {
+	term, err := services.CurrentTerm(database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve the current term"})
+		return
+	}
+	if term == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No term is in progress"})
+		return
+	}
+
+	c.JSON(http.StatusOK, term)
+}
+
+// GetTerm retrieves a single term
+func GetTerm(c *gin.Context) {
+	var term models.Term
+	if err := database.DB.First(&term, c.Param("termId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, term)
+}
+
+// CreateTerm adds an academic term, terms can't overlap (requires admin permission)
+func CreateTerm(c *gin.Context) {
+	var input struct {
+		Name     string    `json:"name" binding:"required"`
+		StartsAt time.Time `json:"starts_at" binding:"required"`
+		EndsAt   time.Time `json:"ends_at" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	term := models.Term{
+		Name:     strings.TrimSpace(input.Name),
+		StartsAt: input.StartsAt,
+		EndsAt:   input.EndsAt,
+	}
+	if msg := validateTerm(database.DB, term); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := database.DB.Create(&term).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A term with this name already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, term)
+}
+
+// UpdateTerm renames a term or moves its dates, closed terms can't change (requires admin permission)
+func UpdateTerm(c *gin.Context) {
+	var input struct {
+		Name     *string    `json:"name"`
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var term models.Term
+	if err := database.DB.First(&term, c.Param("termId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+		return
+	}
+	if term.ClosedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Closed terms can't be modified"})
+		return
+	}
+
+	if input.Name != nil {
+		term.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.StartsAt != nil {
+		term.StartsAt = *input.StartsAt
+	}
+	if input.EndsAt != nil {
+		term.EndsAt = *input.EndsAt
+	}
+	if msg := validateTerm(database.DB, term); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := database.DB.Save(&term).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A term with this name already exists"})
+		return
+	}
+	// Moving the dates moves points between terms
+	services.InvalidateLeaderboards()
+
+	c.JSON(http.StatusOK, term)
+}
+
+// DeleteTerm removes a term that was not closed yet, its points stay in the lifetime totals (requires admin
+// permission)
+func DeleteTerm(c *gin.Context) {
+	result := database.DB.Where("closed_at IS NULL").Delete(&models.Term{}, c.Param("termId"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete term"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		var term models.Term
+		if err := database.DB.First(&term, c.Param("termId")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Closed terms can't be deleted, their standings are final"})
+		return
+	}
+	services.InvalidateLeaderboards()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Term deleted"})
+}
+
+// CloseTerm snapshots the final standings of a term that has ended (requires admin permission)
+func CloseTerm(c *gin.Context) {
+	termID, err := strconv.Atoi(c.Param("termId"))
+	if err != nil || termID <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+		return
+	}
+
+	term, recorded, err := services.CloseTerm(database.DB, uint(termID), c.GetUint("user_id"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+		return
+	case errors.Is(err, services.ErrTermClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "The term is already closed"})
+		return
+	case errors.Is(err, services.ErrTermNotEnded):
+		c.JSON(http.StatusConflict, gin.H{"error": "The term can only be closed once it has ended"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close term"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"term":      term,
+		"standings": recorded,
+	})
+}
+
+// GetTermStandings lists the final standings of a closed term, best first. Users who opted out of
+// leaderboards since are left out.
+func GetTermStandings(c *gin.Context) {
+	var term models.Term
+	if err := database.DB.First(&term, c.Param("termId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+		return
+	}
+	if term.ClosedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The term is not closed yet, see the leaderboard for live standings"})
+		return
+	}
+
+	query := database.DB.Model(&models.TermStanding{}).
+		Select("term_standings.*, users.name, users.photo_thumbnail_url").
+		Joins("JOIN users ON users.id = term_standings.user_id AND users.deleted_at IS NULL AND users.leaderboard_opt_out = ?", false).
+		Where("term_standings.term_id = ?", term.ID)
+
+	page, err := pagination.Paginate(c, query, pagination.Key[termStanding]{
+		Column:   "term_standings.rank",
+		Kind:     pagination.KindInt,
+		IDColumn: "term_standings.id",
+		Value:    func(s termStanding) interface{} { return s.Rank },
+		ID:       func(s termStanding) uint { return s.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve standings")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetUserPoints summarizes a user's points: the balance left to spend, the lifetime total, what they earned in
+// the current term and in every term
+func GetUserPoints(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	terms, err := services.UserTermBalances(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve term balances"})
+		return
+	}
+	current, err := services.CurrentTerm(database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve the current term"})
+		return
+	}
+	var currentBalance *services.TermBalance
+	if current != nil {
+		for i := range terms {
+			if terms[i].TermID == current.ID {
+				currentBalance = &terms[i]
+				break
+			}
+		}
+	}
+
+	lifetime, err := services.LifetimePoints(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lifetime points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance":         user.CurrentPoints, // What can be spent on rewards
+		"lifetime_points": lifetime,
+		"current_term":    currentBalance,
+		"terms":           terms,
+	})
+}
+
+// Helper function to validate a term, returns an error message or ""
+func validateTerm(db *gorm.DB, term models.Term) string {
+	if term.Name == "" {
+		return "name must not be empty"
+	}
+	if !term.StartsAt.Before(term.EndsAt) {
+		return "starts_at must be before ends_at"
+	}
+	var overlapping models.Term
+	err := db.Where("starts_at < ? AND ends_at > ? AND id <> ?", term.EndsAt, term.StartsAt, term.ID).
+		First(&overlapping).Error
+	if err == nil {
+		return "The term overlaps " + overlapping.Name
+	}
+	return ""
+}
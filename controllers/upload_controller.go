@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm/clause"
+)
+
+// UploadEventImage replaces the image of an event with an uploaded one (multipart field "file")
+func UploadEventImage(c *gin.Context) {
+	eventID := c.Param("eventId")
+	var event models.Event
+	if err := database.DB.First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if !canManageEvent(c, event) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to update this event"})
+		return
+	}
+
+	image, ok := storeUploadedImage(c, "events")
+	if !ok {
+		return
+	}
+
+	tx := database.DB.Begin()
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, event.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	event.ImageURL = image.URL
+	event.ThumbnailURL = image.ThumbnailURL
+	event.Sequence++
+	if err := tx.Omit(clause.Associations).Save(&event).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.Header("ETag", eventETag(event))
+	c.JSON(http.StatusOK, event)
+}
+
+// UploadAwardIcon replaces the icon of an award with an uploaded one (requires admin permission)
+func UploadAwardIcon(c *gin.Context) {
+	awardID := c.Param("awardId")
+	var award models.Award
+	if err := database.DB.First(&award, awardID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Award not found"})
+		return
+	}
+
+	image, ok := storeUploadedImage(c, "awards")
+	if !ok {
+		return
+	}
+
+	award.IconURL = image.URL
+	award.IconThumbnailURL = image.ThumbnailURL
+	if err := database.DB.Model(&award).Select("IconURL", "IconThumbnailURL").Updates(&award).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update award"})
+		return
+	}
+
+	c.JSON(http.StatusOK, award)
+}
+
+// UploadUserPhoto replaces the profile photo of a user with an uploaded one (the user themselves or an admin)
+func UploadUserPhoto(c *gin.Context) {
+	id := c.Param("id")
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	image, ok := storeUploadedImage(c, "users")
+	if !ok {
+		return
+	}
+
+	user.PhotoURL = image.URL
+	user.PhotoThumbnailURL = image.ThumbnailURL
+	if err := database.DB.Model(&user).Select("PhotoURL", "PhotoThumbnailURL").Updates(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// Helper function to read the image uploaded in the "file" field and store it, responds with
+// the error and returns false when the upload is missing, too large or not an image
+func storeUploadedImage(c *gin.Context, prefix string) (services.StoredImage, bool) {
+	maxBytes := services.UploadMaxBytes()
+
+	// Leave some room for the rest of the multipart body, the file itself is checked below
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Uploads are limited to %d bytes", maxBytes)})
+			return services.StoredImage{}, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An image must be uploaded in the multipart field 'file'"})
+		return services.StoredImage{}, false
+	}
+	defer file.Close()
+
+	if header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Uploads are limited to %d bytes", maxBytes)})
+		return services.StoredImage{}, false
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the upload"})
+		return services.StoredImage{}, false
+	}
+	if int64(len(data)) > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Uploads are limited to %d bytes", maxBytes)})
+		return services.StoredImage{}, false
+	}
+
+	image, err := services.StoreImage(c.Request.Context(), database.DB, prefix, data, c.GetUint("user_id"))
+	switch {
+	case errors.Is(err, utils.ErrUnsupportedImage):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return image, false
+	case errors.Is(err, utils.ErrImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return image, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store the image"})
+		return image, false
+	}
+	return image, true
+}
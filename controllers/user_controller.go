@@ -5,15 +5,23 @@ import (
 
 	"github.com/open-cmuq/passport-backend/database"
 	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Get all users
 func GetUsers(c *gin.Context) {
-	var users []models.User
-	database.DB.Find(&users)
-	c.JSON(http.StatusOK, users)
+	page, err := pagination.PaginateIfRequested(c, database.DB.Model(&models.User{}), pagination.Key[models.User]{
+		IDColumn: "users.id",
+		ID:       func(u models.User) uint { return u.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve users")
+		return
+	}
+	c.JSON(http.StatusOK, page)
 }
 
 // Get single user by ID
@@ -38,11 +46,12 @@ func UpdateUser(c *gin.Context) {
 
 	// Define input struct with optional fields (pointers)
 	var input struct {
-		Name       *string `json:"name"`
-		GradYear   *int    `json:"grad_year"`
-		Title      *string `json:"title"`
-		Biography  *string `json:"biography"`
-		Department *string `json:"department"`
+		Name              *string `json:"name"`
+		GradYear          *int    `json:"grad_year"`
+		Title             *string `json:"title"`
+		Biography         *string `json:"biography"`
+		Department        *string `json:"department"`
+		LeaderboardOptOut *bool   `json:"leaderboard_opt_out"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -66,12 +75,20 @@ func UpdateUser(c *gin.Context) {
 	if input.Department != nil {
 		user.Department = *input.Department
 	}
+	optOutChanged := input.LeaderboardOptOut != nil && *input.LeaderboardOptOut != user.LeaderboardOptOut
+	if input.LeaderboardOptOut != nil {
+		user.LeaderboardOptOut = *input.LeaderboardOptOut
+	}
 
 	// Save updates
 	if err := database.DB.Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
+	// Opting out must hide the user right away, not at the next refresh
+	if optOutChanged {
+		services.InvalidateLeaderboards()
+	}
 
 	c.JSON(http.StatusOK, user)
 }
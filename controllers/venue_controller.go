@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/pagination"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+)
+
+// venueWithCount is a venue along with the number of events held there
+type venueWithCount struct {
+	models.Venue
+	EventCount int64 `json:"event_count"`
+}
+
+// GetVenues lists every venue with the number of events held there
+func GetVenues(c *gin.Context) {
+	query := database.DB.Table("venues").
+		Select("venues.*, COUNT(events.id) AS event_count").
+		Joins("LEFT JOIN events ON events.venue_id = venues.id AND events.status <> ? AND events.deleted_at IS NULL", models.EventDraft).
+		Group("venues.id")
+
+	page, err := pagination.Paginate(c, query, pagination.Key[venueWithCount]{
+		Column:   "venues.name",
+		Kind:     pagination.KindString,
+		IDColumn: "venues.id",
+		Value:    func(v venueWithCount) interface{} { return v.Name },
+		ID:       func(v venueWithCount) uint { return v.ID },
+	})
+	if err != nil {
+		paginationError(c, err, "Failed to retrieve venues")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetVenue retrieves a single venue
+func GetVenue(c *gin.Context) {
+	venueID := c.Param("venueId")
+	var venue models.Venue
+	if err := database.DB.First(&venue, venueID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Venue not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+// CreateVenue adds a venue to the registry (requires admin permission)
+func CreateVenue(c *gin.Context) {
+	var input struct {
+		Name               string   `json:"name" binding:"required"`
+		Building           string   `json:"building"`
+		Latitude           *float64 `json:"latitude"`
+		Longitude          *float64 `json:"longitude"`
+		Capacity           *int     `json:"capacity"`
+		AccessibilityNotes string   `json:"accessibility_notes"`
+		Aliases            []string `json:"aliases"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	venue := models.Venue{
+		Name:               strings.TrimSpace(input.Name),
+		Building:           input.Building,
+		Latitude:           input.Latitude,
+		Longitude:          input.Longitude,
+		Capacity:           input.Capacity,
+		AccessibilityNotes: input.AccessibilityNotes,
+		Aliases:            cleanAliases(input.Aliases),
+	}
+	if msg := validateVenue(venue); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := database.DB.Create(&venue).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A venue with this name already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, venue)
+}
+
+// UpdateVenue partially updates a venue (requires admin permission)
+func UpdateVenue(c *gin.Context) {
+	venueID := c.Param("venueId")
+	var venue models.Venue
+	if err := database.DB.First(&venue, venueID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Venue not found"})
+		return
+	}
+
+	var input struct {
+		Name               *string                 `json:"name"`
+		Building           *string                 `json:"building"`
+		Latitude           utils.Nullable[float64] `json:"latitude"`
+		Longitude          utils.Nullable[float64] `json:"longitude"`
+		Capacity           utils.Nullable[int]     `json:"capacity"` // null means unlimited
+		AccessibilityNotes *string                 `json:"accessibility_notes"`
+		Aliases            *[]string               `json:"aliases"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Name != nil {
+		venue.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Building != nil {
+		venue.Building = *input.Building
+	}
+	venue.Latitude = input.Latitude.Or(venue.Latitude)
+	venue.Longitude = input.Longitude.Or(venue.Longitude)
+	venue.Capacity = input.Capacity.Or(venue.Capacity)
+	if input.AccessibilityNotes != nil {
+		venue.AccessibilityNotes = *input.AccessibilityNotes
+	}
+	if input.Aliases != nil {
+		venue.Aliases = cleanAliases(*input.Aliases)
+	}
+	if msg := validateVenue(venue); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := database.DB.Save(&venue).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A venue with this name already exists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+// DeleteVenue removes a venue, its events keep their free-text location (requires admin permission)
+func DeleteVenue(c *gin.Context) {
+	venueID := c.Param("venueId")
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Event{}).Where("venue_id = ?", venueID).
+			Update("venue_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Venue{}, venueID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Venue not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete venue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Venue deleted"})
+}
+
+// MapVenueLocations links events without a venue to the venue their free-text location matches,
+// pass dry_run=true to only see the matches (requires admin permission)
+func MapVenueLocations(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+	matches, err := services.MapEventLocations(database.DB, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to map event locations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": dryRun,
+		"matches": matches,
+	})
+}
+
+// Helper function to validate a venue, returns an error message or ""
+func validateVenue(venue models.Venue) string {
+	if venue.Name == "" {
+		return "name must not be empty"
+	}
+	if (venue.Latitude == nil) != (venue.Longitude == nil) {
+		return "latitude and longitude must both be nil or both have values"
+	}
+	if venue.Latitude != nil && (*venue.Latitude < -90 || *venue.Latitude > 90 || *venue.Longitude < -180 || *venue.Longitude > 180) {
+		return "latitude must be between -90 and 90 and longitude between -180 and 180"
+	}
+	return validateRSVPSettings(venue.Capacity, nil, nil)
+}
+
+// Helper function to trim venue aliases and drop empty ones
+func cleanAliases(aliases []string) models.StringList {
+	cleaned := models.StringList{}
+	for _, alias := range aliases {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			cleaned = append(cleaned, alias)
+		}
+	}
+	return cleaned
+}
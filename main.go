@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/open-cmuq/passport-backend/database"
+	"github.com/open-cmuq/passport-backend/meetings"
 	"github.com/open-cmuq/passport-backend/models"
 	"github.com/open-cmuq/passport-backend/routes"
+	"github.com/open-cmuq/passport-backend/services"
+	"github.com/open-cmuq/passport-backend/storage"
 	"github.com/open-cmuq/passport-backend/utils"
 	"gorm.io/gorm"
 )
@@ -23,10 +28,57 @@ func main() {
 	database.Connect()
 	// Create ENUM types if they don't exist
 	createEnumTypes(database.DB)
+	// Use our own join table for user awards so it records when each award was granted
+	if err := database.DB.SetupJoinTable(&models.User{}, "AwardsEarned", &models.UserBadge{}); err != nil {
+		log.Fatalf("Failed to set up the user badges join table: %v", err)
+	}
 	// Auto-migrate models
-	if err := database.DB.AutoMigrate(&models.User{}, &models.Event{}, &models.Attendance{}, &models.Award{}); err != nil {
+	if err := database.DB.AutoMigrate(
+		&models.User{},
+		&models.Event{},
+		&models.Attendance{},
+		&models.Award{},
+		&models.RSVP{},
+		&models.EventSeries{},
+		&models.EventSeriesException{},
+		&models.Category{},
+		&models.Tag{},
+		&models.PointTransaction{},
+		&models.EventTemplate{},
+		&models.Blob{},
+		&models.Survey{},
+		&models.SurveyQuestion{},
+		&models.SurveyResponse{},
+		&models.SurveyAnswer{},
+		&models.Venue{},
+		&models.JoinLink{},
+		&models.ReconciliationRun{},
+		&models.ReconciliationItem{},
+		&models.Term{},
+		&models.TermStanding{},
+		&models.Reward{},
+		&models.Redemption{},
+		&models.Team{},
+		&models.TeamMembership{},
+		&models.Competition{},
+		&models.UserStreak{},
+		&models.DataMigration{},
+	); err != nil {
 		log.Fatalf("Failed to auto-migrate: %v", err)
 	}
+	// Keep the points ledger append-only and make it explain every existing balance
+	createLedgerGuards(database.DB)
+	backfillOpeningBalances(database.DB)
+	// Record the attendance streaks of users who attended before streaks were tracked
+	backfillStreaks(database.DB)
+	// Report the events whose free-text locations match a venue, admins link them with POST /venues/map-locations
+	mapEventLocations(database.DB)
+	// Set up the blob store for uploads
+	storage.Init()
+	// Set up the meeting platforms that report online attendance
+	meetings.Init()
+	// Create indexes AutoMigrate can't express
+	createSearchIndexes(database.DB)
 
 	// Initialize Gin
 	gin.SetMode(gin.DebugMode)
@@ -39,8 +91,8 @@ func main() {
 		router.Use(cors.New(cors.Config{
 			AllowAllOrigins:  true, // Allow all origins
 			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-			AllowHeaders:     []string{"*"}, // Allow all headers
-			ExposeHeaders:    []string{"Content-Length"},
+			AllowHeaders:     []string{"*"},                      // Allow all headers
+			ExposeHeaders:    []string{"Content-Length", "ETag"}, // ETag is sent back as If-Match when updating events
 			AllowCredentials: true,
 		}))
 	} else {
@@ -60,6 +112,76 @@ func main() {
 		}
 	}()
 
+	// Start the background purge of deleted events
+	go func() {
+		for {
+			time.Sleep(1 * time.Hour) // Run every hour
+			purged, err := services.PurgeDeletedEvents(database.DB, time.Now().Add(-services.EventPurgeAfter()))
+			if err != nil {
+				log.Printf("Failed to purge deleted events: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted events", purged)
+			}
+		}
+	}()
+
+	// Start the background cleanup of uploaded files nothing points to anymore
+	go func() {
+		for {
+			time.Sleep(1 * time.Hour) // Run every hour
+			deleted, err := services.CleanupOrphanedBlobs(context.Background(), database.DB)
+			if err != nil {
+				log.Printf("Failed to clean up orphaned uploads: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Deleted %d orphaned uploads", deleted)
+			}
+		}
+	}()
+
+	// Start the background reconciliation of point balances, it only reports discrepancies
+	// unless POINTS_RECONCILE_APPLY is set
+	go func() {
+		for {
+			time.Sleep(services.ReconcileInterval())
+			run, err := services.ReconcilePoints(database.DB, os.Getenv("POINTS_RECONCILE_APPLY") != "true", 0)
+			if err != nil {
+				log.Printf("Failed to reconcile points: %v", err)
+				continue
+			}
+			if run.Discrepancies > 0 {
+				log.Printf("Reconciliation run #%d found %d balance discrepancies, corrected %d", run.ID, run.Discrepancies, run.Corrected)
+			}
+		}
+	}()
+
+	// Start the background refresh of cached leaderboards
+	go func() {
+		for {
+			time.Sleep(services.LeaderboardRefreshInterval())
+			if _, err := services.RefreshLeaderboards(database.DB); err != nil {
+				log.Printf("Failed to refresh leaderboards: %v", err)
+			}
+		}
+	}()
+
+	// Start the background declaration of the winners of competitions that have ended, ties are left to admins
+	go func() {
+		for {
+			time.Sleep(1 * time.Hour) // Run every hour
+			declared, err := services.DeclareEndedCompetitions(database.DB)
+			if err != nil {
+				log.Printf("Failed to declare competition winners: %v", err)
+			}
+			if declared > 0 {
+				log.Printf("Declared the winners of %d competitions", declared)
+			}
+		}
+	}()
+
 	// Start server
 	log.Println("Server running on :8080")
 	router.Run("0.0.0.0:8080")
@@ -84,3 +206,65 @@ func createEnumTypes(db *gorm.DB) {
 		log.Fatalf("Failed to create user_role ENUM type: %v", err)
 	}
 }
+
+func createLedgerGuards(db *gorm.DB) {
+	// Reject changes to ledger entries that bypass the GORM hooks, corrections are new transactions
+	if err := db.Exec(`CREATE OR REPLACE FUNCTION point_transactions_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'point_transactions is append-only, post a correcting transaction instead';
+	END;
+	$$ LANGUAGE plpgsql;`).Error; err != nil {
+		log.Fatalf("Failed to create the ledger guard function: %v", err)
+	}
+	if err := db.Exec(`DROP TRIGGER IF EXISTS point_transactions_append_only ON point_transactions`).Error; err != nil {
+		log.Fatalf("Failed to replace the ledger guard trigger: %v", err)
+	}
+	if err := db.Exec(`CREATE TRIGGER point_transactions_append_only BEFORE UPDATE OR DELETE ON point_transactions
+		FOR EACH ROW EXECUTE FUNCTION point_transactions_append_only()`).Error; err != nil {
+		log.Fatalf("Failed to create the ledger guard trigger: %v", err)
+	}
+}
+
+func backfillOpeningBalances(db *gorm.DB) {
+	opened, err := services.BackfillOpeningBalances(db)
+	if err != nil {
+		log.Fatalf("Failed to backfill opening balances: %v", err)
+	}
+	if opened > 0 {
+		log.Printf("Recorded opening balances for %d users", opened)
+	}
+}
+
+func backfillStreaks(db *gorm.DB) {
+	recorded, err := services.BackfillStreaks(db)
+	if err != nil {
+		log.Fatalf("Failed to backfill attendance streaks: %v", err)
+	}
+	if recorded > 0 {
+		log.Printf("Recorded attendance streaks for %d users", recorded)
+	}
+}
+
+func mapEventLocations(db *gorm.DB) {
+	matches, err := services.MapEventLocations(db, true)
+	if err != nil {
+		log.Fatalf("Failed to map event locations to venues: %v", err)
+	}
+	matched := 0
+	for _, match := range matches {
+		if match.VenueID != nil {
+			matched++
+		}
+	}
+	if matched > 0 {
+		log.Printf("%d event locations match a venue, link them with POST /venues/map-locations", matched)
+	}
+}
+
+func createSearchIndexes(db *gorm.DB) {
+	// Create the GIN index backing full-text event search
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_events_search ON events USING GIN ((` +
+		models.EventSearchVector + `))`).Error; err != nil {
+		log.Fatalf("Failed to create event search index: %v", err)
+	}
+}
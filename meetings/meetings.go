@@ -0,0 +1,51 @@
+package meetings
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidPayload   = errors.New("invalid webhook payload")
+)
+
+// Participant is someone the meeting platform saw in a meeting
+type Participant struct {
+	Email    string
+	JoinedAt time.Time
+}
+
+// Report is the attendance of one meeting as delivered by a webhook
+type Report struct {
+	MeetingID    string
+	Participants []Participant
+}
+
+// Provider turns the attendance webhooks of a meeting platform into reports
+type Provider interface {
+	// ParseWebhook checks the request really comes from the platform and extracts the attendance,
+	// failing with ErrInvalidSignature or ErrInvalidPayload
+	ParseWebhook(r *http.Request, body []byte) (*Report, error)
+}
+
+// providers holds the configured providers by the name used in their webhook URL, set up by Init
+var providers = map[string]Provider{}
+
+// Init sets up the meeting providers from the environment. Only the local stub exists for now,
+// it is enabled by setting MEETING_STUB_SECRET.
+func Init() {
+	if secret := os.Getenv("MEETING_STUB_SECRET"); secret != "" {
+		providers["stub"] = &StubProvider{Secret: secret}
+		log.Println("Stub meeting provider enabled")
+	}
+}
+
+// Lookup returns the provider registered under name
+func Lookup(name string) (Provider, bool) {
+	provider, ok := providers[name]
+	return provider, ok
+}
@@ -0,0 +1,55 @@
+package meetings
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StubProvider accepts a minimal webhook format for local development and tests, requests are signed
+// with the hex HMAC-SHA256 of their body in the X-Stub-Signature header, like real platforms do
+type StubProvider struct {
+	Secret string
+}
+
+// stubPayload is the body of a stub webhook:
+// {"meeting_id": "123", "participants": [{"email": "a@example.com", "joined_at": "2025-01-01T10:00:00Z"}]}
+type stubPayload struct {
+	MeetingID    string `json:"meeting_id"`
+	Participants []struct {
+		Email    string     `json:"email"`
+		JoinedAt *time.Time `json:"joined_at"` // Defaults to the time the webhook arrived
+	} `json:"participants"`
+}
+
+func (p *StubProvider) ParseWebhook(r *http.Request, body []byte) (*Report, error) {
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write(body)
+	signature, err := hex.DecodeString(r.Header.Get("X-Stub-Signature"))
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var payload stubPayload
+	if err := json.Unmarshal(body, &payload); err != nil || strings.TrimSpace(payload.MeetingID) == "" {
+		return nil, ErrInvalidPayload
+	}
+
+	report := &Report{MeetingID: strings.TrimSpace(payload.MeetingID)}
+	now := time.Now()
+	for _, participant := range payload.Participants {
+		joinedAt := now
+		if participant.JoinedAt != nil {
+			joinedAt = *participant.JoinedAt
+		}
+		report.Participants = append(report.Participants, Participant{
+			Email:    strings.TrimSpace(participant.Email),
+			JoinedAt: joinedAt,
+		})
+	}
+	return report, nil
+}
@@ -50,6 +50,19 @@ func AdminOnlyMiddleware() gin.HandlerFunc {
 	}
 }
 
+// StaffOnlyMiddleware restricts access to staff and admin users
+func StaffOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role != "admin" && role != "staff" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Staff access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // OwnershipMiddleware ensures that a user can only update their own data (unless they are an admin)
 func OwnershipMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -4,11 +4,20 @@ import (
 	"time"
 )
 
+type AttendanceSource string
+
+const (
+	AttendanceManual   AttendanceSource = "manual"    // Scanned or added by staff
+	AttendanceJoinLink AttendanceSource = "join_link" // Followed their tracked join link
+	AttendanceWebhook  AttendanceSource = "webhook"   // Reported by the meeting platform
+)
+
 type Attendance struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	UserID      uint      `gorm:"not null" json:"user_id"`
 	EventID     uint      `gorm:"not null" json:"event_id"`
 	ScannedTime time.Time `gorm:"not null" json:"scanned_time"`
+	Source      string    `gorm:"size:20;check:source IN ('manual', 'join_link', 'webhook');default:'manual'" json:"source"`
 
 	// Relationships
 	User  User  `gorm:"foreignKey:UserID" json:"user"`
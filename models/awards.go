@@ -2,18 +2,21 @@ package models
 
 import (
 	"time"
+
+	"github.com/open-cmuq/passport-backend/rules"
 )
 
 type Award struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"size:255;not null" json:"name"`
-	Description string    `gorm:"type:text" json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Points      int       `gorm:"default:0" json:"points"`
-	IconURL     string    `gorm:"size:512" json:"icon_url"`
+	ID               uint            `gorm:"primaryKey" json:"id"`
+	Name             string          `gorm:"size:255;not null" json:"name"`
+	Description      string          `gorm:"type:text" json:"description"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	Points           int             `gorm:"default:0" json:"points"`    // Balance needed to earn the award when it has no criteria
+	Criteria         *rules.Criteria `gorm:"type:jsonb" json:"criteria"` // Rules deciding who earns the award, replaces the points threshold
+	IconURL          string          `gorm:"size:512" json:"icon_url"`
+	IconThumbnailURL string          `gorm:"size:512" json:"icon_thumbnail_url"` // Set when the icon was uploaded
 
 	// Relationships
 	Events []Event `gorm:"many2many:event_awards" json:"events"` // Many-to-many relationship with events
 }
-
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Blob tracks a file we uploaded to the blob store, so files nothing points to anymore can be cleaned up
+type Blob struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Key          string    `gorm:"size:512;not null;unique" json:"key"`
+	URL          string    `gorm:"size:1024;not null;index" json:"url"`
+	ContentType  string    `gorm:"size:100" json:"content_type"`
+	Size         int64     `json:"size"`
+	UploadedByID *uint     `json:"uploaded_by_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Category is a managed classification of events (recycling drive, talk, cleanup, workshop, ...)
+type Category struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:255;not null;unique" json:"name"`
+	Slug        string    `gorm:"size:255;not null;unique" json:"slug"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Tag is a free-form label organizers can attach to events
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:100;not null;unique" json:"name"` // Lowercase and trimmed
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import (
+	"time"
+)
+
+// DataMigration records a one-time data migration that ran, so it never runs again
+type DataMigration struct {
+	Name      string    `gorm:"primaryKey;size:100" json:"name"`
+	AppliedAt time.Time `gorm:"type:timestamptz;not null" json:"applied_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// EventSeries is the parent of a set of recurring events materialized from an RRULE
+type EventSeries struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Name             string    `gorm:"size:255;not null" json:"name"`
+	Description      string    `gorm:"type:text" json:"description"`
+	Location         string    `gorm:"size:255" json:"location"`
+	RRule            string    `gorm:"size:255;not null" json:"rrule"`              // RFC 5545 recurrence rule, see utils.ParseRRule
+	StartTime        time.Time `gorm:"type:timestamptz;not null" json:"start_time"` // DTSTART, the start of the first occurrence
+	DurationMinutes  int       `gorm:"not null" json:"duration_minutes"`            // Length of every occurrence
+	OrganizerID      uint      `gorm:"not null" json:"organizer_id"`                // ID of the user who organized the series
+	PointsAllocation int       `gorm:"default:0" json:"points_allocation"`
+	ImageURL         string    `gorm:"size:512" json:"image_url"`
+	Capacity         *int      `json:"capacity"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Relationships
+	Organizer  User                   `gorm:"foreignKey:OrganizerID" json:"organizer"`
+	Exceptions []EventSeriesException `gorm:"foreignKey:SeriesID" json:"exceptions"`
+	Events     []Event                `gorm:"foreignKey:SeriesID" json:"events,omitempty"`
+}
+
+// EventSeriesException is an EXDATE, an occurrence of the rule that must not be materialized
+type EventSeriesException struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	SeriesID          uint      `gorm:"not null;uniqueIndex:idx_series_exception" json:"series_id"`
+	OriginalStartTime time.Time `gorm:"type:timestamptz;not null;uniqueIndex:idx_series_exception" json:"original_start_time"`
+	CreatedAt         time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// EventTemplate holds reusable defaults organizers can start new events from
+type EventTemplate struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Name             string    `gorm:"size:255;not null;unique" json:"name"` // Name of the template, not of the events created from it
+	Description      string    `gorm:"type:text" json:"description"`
+	Location         string    `gorm:"size:255" json:"location"`
+	PointsAllocation int       `gorm:"default:0" json:"points_allocation"`
+	ImageURL         string    `gorm:"size:512" json:"icon_url"`
+	CategoryID       *uint     `gorm:"index" json:"category_id"`
+	Capacity         *int      `json:"capacity"`
+	CreatedByID      uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Relationships
+	CreatedBy User      `gorm:"foreignKey:CreatedByID" json:"created_by"`
+	Category  *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	Awards    []Award   `gorm:"many2many:event_template_awards" json:"awards"`
+}
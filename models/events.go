@@ -2,20 +2,97 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
+type EventStatus string
+
+const (
+	EventDraft     EventStatus = "draft"
+	EventPublished EventStatus = "published"
+	EventCancelled EventStatus = "cancelled"
+	EventArchived  EventStatus = "archived"
+)
+
+type EventMode string
+
+const (
+	EventInPerson EventMode = "in_person"
+	EventOnline   EventMode = "online"
+	EventHybrid   EventMode = "hybrid"
+)
+
+// eventStatusTransitions lists the statuses an event may move to from each status
+var eventStatusTransitions = map[EventStatus][]EventStatus{
+	EventDraft:     {EventPublished, EventCancelled},
+	EventPublished: {EventCancelled, EventArchived},
+	EventCancelled: {EventArchived},
+	EventArchived:  {},
+}
+
+// EventSearchVector is the weighted full-text document of an event, the GIN index in main.go is built on
+// this exact expression so queries must use it verbatim for Postgres to pick the index up
+const EventSearchVector = `setweight(to_tsvector('english', coalesce(name, '')), 'A') || ` +
+	`setweight(to_tsvector('english', coalesce(description, '')), 'B') || ` +
+	`setweight(to_tsvector('english', coalesce(location, '')), 'C')`
+
 type Event struct {
-	ID               uint       `gorm:"primaryKey" json:"id"`
-	Name             string     `gorm:"size:255;not null" json:"name"`
-	Description      string     `gorm:"type:text" json:"description"`
-	Location         string     `gorm:"size:255" json:"location"`
-	StartTime        *time.Time `gorm:"type:timestamptz" json:"start_time"` // Pointer to time.Time, allows NULL
-	EndTime          *time.Time `gorm:"type:timestamptz" json:"end_time"`   // Pointer to time.Time, allows NULL
-	OrganizerID      uint       `gorm:"not null" json:"organizer_id"`       // ID of the user who organized the event
-	PointsAllocation int        `gorm:"default:0" json:"points_allocation"`
-	ImageURL         string     `gorm:"size:512" json:"icon_url"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	Name               string         `gorm:"size:255;not null" json:"name"`
+	Description        string         `gorm:"type:text" json:"description"`
+	Location           string         `gorm:"size:255" json:"location"`
+	VenueID            *uint          `gorm:"index" json:"venue_id"`              // Registered venue, Location keeps a free-text name
+	JoinURL            string         `gorm:"size:512" json:"-"`                  // Stream link, only revealed to RSVP'd users by GetEventJoinLink
+	MeetingID          string         `gorm:"size:255;index" json:"-"`            // Meeting on the streaming platform, matches webhook attendance to the event
+	StartTime          *time.Time     `gorm:"type:timestamptz" json:"start_time"` // Pointer to time.Time, allows NULL
+	EndTime            *time.Time     `gorm:"type:timestamptz" json:"end_time"`   // Pointer to time.Time, allows NULL
+	OrganizerID        uint           `gorm:"not null" json:"organizer_id"`       // ID of the user who organized the event
+	PointsAllocation   int            `gorm:"default:0" json:"points_allocation"`
+	ImageURL           string         `gorm:"size:512" json:"icon_url"`
+	ThumbnailURL       string         `gorm:"size:512" json:"thumbnail_url"` // Set when the image was uploaded
+	CategoryID         *uint          `gorm:"index" json:"category_id"`
+	Mode               string         `gorm:"size:20;check:mode IN ('in_person', 'online', 'hybrid');default:'in_person'" json:"mode"`                   // Online and hybrid events can be joined through a link
+	Status             string         `gorm:"size:20;check:status IN ('draft', 'published', 'cancelled', 'archived');default:'published'" json:"status"` // Existing rows and new events default to published
+	CancellationReason string         `gorm:"type:text" json:"cancellation_reason,omitempty"`
+	Sequence           int            `gorm:"default:0" json:"sequence"`                                             // Revision number, bumped on every change (iCalendar SEQUENCE)
+	Capacity           *int           `json:"capacity"`                                                              // Maximum number of confirmed RSVPs, NULL means unlimited
+	RSVPDeadline       *time.Time     `gorm:"type:timestamptz" json:"rsvp_deadline"`                                 // RSVPs are refused after this time, NULL means no deadline
+	SeriesID           *uint          `gorm:"index" json:"series_id"`                                                // Parent series for recurring events
+	OriginalStartTime  *time.Time     `gorm:"type:timestamptz" json:"original_start_time"`                           // Slot of the occurrence in its series rule (RECURRENCE-ID)
+	Detached           bool           `gorm:"default:false" json:"detached"`                                         // Edited on its own, series-wide edits skip it
+	UpdatedAt          time.Time      `gorm:"type:timestamptz;not null;default:CURRENT_TIMESTAMP" json:"updated_at"` // Existing rows get the migration time
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"deleted_at"`                                               // Deleted events can be restored until they are purged
+
+	// Full-text search results, only populated by GetEvents when searching with q
+	SearchRank           *float64 `gorm:"->;-:migration" json:"search_rank,omitempty"`
+	NameHighlight        *string  `gorm:"->;-:migration" json:"name_highlight,omitempty"`
+	DescriptionHighlight *string  `gorm:"->;-:migration" json:"description_highlight,omitempty"`
 
 	// Relationships
-	Organizer User    `gorm:"foreignKey:OrganizerID" json:"organizer"`
-	Awards    []Award `gorm:"many2many:event_awards" json:"awards"` // Many-to-many relationship with awards
+	Organizer User      `gorm:"foreignKey:OrganizerID" json:"organizer"`
+	Awards    []Award   `gorm:"many2many:event_awards" json:"awards"` // Many-to-many relationship with awards
+	Category  *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	Venue     *Venue    `gorm:"foreignKey:VenueID" json:"venue,omitempty"`
+	Tags      []Tag     `gorm:"many2many:event_tags" json:"tags"`
+}
+
+// CanTransitionTo reports whether the event may move from its current status to the given one
+func (e *Event) CanTransitionTo(status EventStatus) bool {
+	for _, allowed := range eventStatusTransitions[EventStatus(e.Status)] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStreamed reports whether the event can be joined online
+func (e *Event) IsStreamed() bool {
+	return e.Mode == string(EventOnline) || e.Mode == string(EventHybrid)
+}
+
+// AcceptsAttendance reports whether attendance may be recorded for the event
+func (e *Event) AcceptsAttendance() bool {
+	return e.Status == string(EventPublished) || e.Status == string(EventArchived)
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// JoinLink is a user's personal link to an online event, following it records their attendance
+type JoinLink struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	EventID    uint       `gorm:"not null;uniqueIndex:idx_join_links_event_user" json:"event_id"`
+	UserID     uint       `gorm:"not null;uniqueIndex:idx_join_links_event_user" json:"user_id"`
+	Token      string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ClickCount int        `gorm:"default:0" json:"click_count"`
+	LastUsedAt *time.Time `gorm:"type:timestamptz" json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PointReason string
+
+const (
+	PointReasonOpeningBalance      PointReason = "opening_balance"
+	PointReasonAttendance          PointReason = "attendance"
+	PointReasonAttendanceRemoved   PointReason = "attendance_removed"
+	PointReasonEventPointsChanged  PointReason = "event_points_changed"
+	PointReasonEventDeleted        PointReason = "event_deleted"
+	PointReasonEventRestored       PointReason = "event_restored"
+	PointReasonSurveyCompleted     PointReason = "survey_completed"
+	PointReasonSurveyBonusRevoked  PointReason = "survey_bonus_revoked"
+	PointReasonSurveyBonusRestored PointReason = "survey_bonus_restored"
+	PointReasonSurveyBonusRemoved  PointReason = "survey_bonus_removed"
+	PointReasonReconciliation      PointReason = "reconciliation"
+	PointReasonRedemption          PointReason = "redemption"
+	PointReasonRedemptionRefund    PointReason = "redemption_refund"
+)
+
+// ErrLedgerAppendOnly is returned when something tries to change or remove a ledger entry
+var ErrLedgerAppendOnly = errors.New("point transactions are append-only, post a correcting transaction instead")
+
+// PointTransaction records a change to a user's balance and why it happened. The ledger is append-only,
+// a user's current_points is a cache of the sum of their transactions.
+type PointTransaction struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	Delta        int       `gorm:"not null" json:"delta"` // Added to current_points, negative for deductions
+	Reason       string    `gorm:"size:50;not null" json:"reason"`
+	EventID      *uint     `gorm:"index" json:"event_id"`      // Event the change relates to, if any
+	AttendanceID *uint     `gorm:"index" json:"attendance_id"` // Attendance the change relates to, if any
+	ActorID      *uint     `json:"actor_id"`                   // User who caused the change, NULL for system changes
+	Note         string    `gorm:"type:text" json:"note,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Only populated by the points history
+	BalanceAfter *int    `gorm:"->;-:migration" json:"balance_after,omitempty"`
+	EventName    *string `gorm:"->;-:migration" json:"event_name,omitempty"`
+}
+
+// BeforeUpdate keeps ledger entries from being changed through GORM, a trigger guards the table itself
+func (t *PointTransaction) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerAppendOnly
+}
+
+// BeforeDelete keeps ledger entries from being removed through GORM
+func (t *PointTransaction) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerAppendOnly
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// ReconciliationRun records one check of every balance against what the user should have
+type ReconciliationRun struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	DryRun        bool       `gorm:"not null" json:"dry_run"`
+	TriggeredByID *uint      `json:"triggered_by_id"` // Admin who started the run, NULL for the scheduled job
+	UsersChecked  int        `gorm:"not null" json:"users_checked"`
+	Discrepancies int        `gorm:"not null" json:"discrepancies"`
+	Corrected     int        `gorm:"not null" json:"corrected"`      // Balances fixed, always 0 for dry runs
+	NetCorrection int        `gorm:"not null" json:"net_correction"` // Sum of the posted corrections
+	StartedAt     time.Time  `gorm:"type:timestamptz;not null" json:"started_at"`
+	FinishedAt    *time.Time `gorm:"type:timestamptz" json:"finished_at"`
+
+	// Relationships
+	Items []ReconciliationItem `gorm:"foreignKey:RunID" json:"items,omitempty"`
+}
+
+// ReconciliationItem is a user whose balance didn't match during a reconciliation run
+type ReconciliationItem struct {
+	ID             uint  `gorm:"primaryKey" json:"id"`
+	RunID          uint  `gorm:"not null;index" json:"run_id"`
+	UserID         uint  `gorm:"not null;index" json:"user_id"`
+	CachedPoints   int   `gorm:"not null" json:"cached_points"`   // current_points when checked
+	LedgerPoints   int   `gorm:"not null" json:"ledger_points"`   // Sum of the user's ledger
+	ExpectedPoints int   `gorm:"not null" json:"expected_points"` // Recomputed from attendances and the other ledger entries
+	Correction     int   `gorm:"not null" json:"correction"`      // Delta posted to bring the ledger to the expected points
+	TransactionID  *uint `json:"transaction_id"`                  // Ledger entry of the correction, NULL for dry runs
+	Applied        bool  `gorm:"not null" json:"applied"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+type RedemptionStatus string
+
+const (
+	RedemptionPending   RedemptionStatus = "pending"   // Points debited, waiting to be picked up
+	RedemptionFulfilled RedemptionStatus = "fulfilled" // Handed over by staff
+	RedemptionCancelled RedemptionStatus = "cancelled" // Points refunded and the item back in stock
+)
+
+// Reward is an item of the rewards catalog users can spend their points on
+type Reward struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Name           string     `gorm:"size:255;not null" json:"name"`
+	Description    string     `gorm:"type:text" json:"description"`
+	ImageURL       string     `gorm:"size:512" json:"image_url"`
+	Cost           int        `gorm:"not null;check:cost > 0" json:"cost"`
+	Stock          *int       `gorm:"check:stock >= 0" json:"stock"` // Items left, NULL for unlimited
+	AvailableFrom  *time.Time `gorm:"type:timestamptz" json:"available_from"`
+	AvailableUntil *time.Time `gorm:"type:timestamptz" json:"available_until"`
+	Active         bool       `gorm:"not null" json:"active"` // Inactive rewards are hidden from the catalog
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// AvailableAt reports whether the reward can be redeemed at the given time, stock aside
+func (r Reward) AvailableAt(at time.Time) bool {
+	if !r.Active {
+		return false
+	}
+	if r.AvailableFrom != nil && at.Before(*r.AvailableFrom) {
+		return false
+	}
+	return r.AvailableUntil == nil || at.Before(*r.AvailableUntil)
+}
+
+// Redemption is a user spending points on a reward. The debit and the refund of a cancellation are ledger
+// entries, the redemption points at them.
+type Redemption struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	RewardID            uint       `gorm:"not null;index" json:"reward_id"`
+	UserID              uint       `gorm:"not null;index" json:"user_id"`
+	Cost                int        `gorm:"not null" json:"cost"` // What the reward cost when it was redeemed
+	Status              string     `gorm:"size:20;check:status IN ('pending', 'fulfilled', 'cancelled');default:'pending';index" json:"status"`
+	PickupCode          string     `gorm:"size:16;not null;uniqueIndex" json:"pickup_code"` // Shown to staff when picking up
+	DebitTransactionID  uint       `gorm:"not null" json:"debit_transaction_id"`
+	RefundTransactionID *uint      `json:"refund_transaction_id"`
+	FulfilledAt         *time.Time `json:"fulfilled_at"`
+	FulfilledByID       *uint      `json:"fulfilled_by_id"`
+	CancelledAt         *time.Time `json:"cancelled_at"`
+	CancelledByID       *uint      `json:"cancelled_by_id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+
+	// Relationships
+	Reward *Reward `gorm:"foreignKey:RewardID" json:"reward,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+type RSVPStatus string
+
+const (
+	RSVPGoing      RSVPStatus = "going"
+	RSVPWaitlisted RSVPStatus = "waitlisted"
+	RSVPCancelled  RSVPStatus = "cancelled"
+)
+
+type RSVP struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_rsvps_event_user" json:"user_id"`
+	EventID     uint      `gorm:"not null;uniqueIndex:idx_rsvps_event_user" json:"event_id"`
+	Status      string    `gorm:"size:20;check:status IN ('going', 'waitlisted', 'cancelled');default:'going'" json:"status"`
+	RespondedAt time.Time `gorm:"not null" json:"responded_at"` // Last time the user (re-)RSVP'd, orders the waitlist
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"user"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+type QuestionType string
+
+const (
+	QuestionRating QuestionType = "rating" // 1 to SurveyRatingMax
+	QuestionChoice QuestionType = "choice" // One (or several) of the options
+	QuestionText   QuestionType = "text"   // Free text
+)
+
+// SurveyRatingMax is the highest answer to a rating question, the lowest being 1
+const SurveyRatingMax = 5
+
+// Survey is the feedback survey of an event, answered by its attendees
+type Survey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	EventID     uint       `gorm:"not null;uniqueIndex" json:"event_id"`
+	Title       string     `gorm:"size:255;not null" json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	BonusPoints int        `gorm:"default:0" json:"bonus_points"` // Credited to attendees who complete the survey
+	ClosesAt    *time.Time `gorm:"type:timestamptz" json:"closes_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relationships
+	Questions []SurveyQuestion `gorm:"foreignKey:SurveyID" json:"questions"`
+}
+
+// IsClosed reports whether the survey stopped taking responses
+func (s *Survey) IsClosed() bool {
+	return s.ClosesAt != nil && time.Now().After(*s.ClosesAt)
+}
+
+type SurveyQuestion struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	SurveyID      uint       `gorm:"not null;index" json:"survey_id"`
+	Position      int        `gorm:"not null" json:"position"`
+	Type          string     `gorm:"size:20;not null;check:type IN ('rating', 'choice', 'text')" json:"type"`
+	Prompt        string     `gorm:"type:text;not null" json:"prompt"`
+	Options       StringList `json:"options"`                             // Choice questions only
+	AllowMultiple bool       `gorm:"default:false" json:"allow_multiple"` // Choice questions only
+	Required      bool       `gorm:"default:false" json:"required"`
+}
+
+// SurveyResponse is one attendee's answers to a survey, each attendee answers once
+type SurveyResponse struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SurveyID  uint      `gorm:"not null;uniqueIndex:idx_survey_responses_survey_user" json:"survey_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_survey_responses_survey_user" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Answers []SurveyAnswer `gorm:"foreignKey:ResponseID" json:"answers"`
+}
+
+type SurveyAnswer struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ResponseID uint       `gorm:"not null;index" json:"response_id"`
+	QuestionID uint       `gorm:"not null;index" json:"question_id"`
+	Rating     *int       `json:"rating,omitempty"`
+	Choices    StringList `json:"choices,omitempty"`
+	Text       string     `gorm:"type:text" json:"text,omitempty"`
+}
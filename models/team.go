@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+type TeamKind string
+
+const (
+	TeamHouse TeamKind = "house" // Residential houses, a user belongs to one at a time and is assigned by admins
+	TeamClub  TeamKind = "club"  // Clubs, users join as many as they like
+)
+
+// Exclusive reports whether users can only belong to one team of this kind at a time
+func (k TeamKind) Exclusive() bool {
+	return k == TeamHouse
+}
+
+// Team is a group of users competing together, its points come from what its members attend
+type Team struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:255;not null;unique" json:"name"`
+	Kind        string    `gorm:"size:20;not null;check:kind IN ('house', 'club');index" json:"kind"`
+	Description string    `gorm:"type:text" json:"description"`
+	ImageURL    string    `gorm:"size:512" json:"image_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TeamMembership is a period a user belonged to a team. Leaving ends the membership instead of deleting it so
+// the team keeps the points earned while the user was a member.
+type TeamMembership struct {
+	ID       uint       `gorm:"primaryKey" json:"id"`
+	TeamID   uint       `gorm:"not null;index;uniqueIndex:idx_team_memberships_active,where:left_at IS NULL" json:"team_id"`
+	UserID   uint       `gorm:"not null;index;uniqueIndex:idx_team_memberships_active,where:left_at IS NULL" json:"user_id"`
+	JoinedAt time.Time  `gorm:"type:timestamptz;not null" json:"joined_at"`
+	LeftAt   *time.Time `gorm:"type:timestamptz" json:"left_at"` // NULL while the user is a member
+}
+
+// Competition is a time-boxed challenge between teams, won by the team whose members earned the most points
+// by attending events between its start and end
+type Competition struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Name         string     `gorm:"size:255;not null" json:"name"`
+	Description  string     `gorm:"type:text" json:"description"`
+	StartsAt     time.Time  `gorm:"type:timestamptz;not null" json:"starts_at"`
+	EndsAt       time.Time  `gorm:"type:timestamptz;not null;index" json:"ends_at"` // Exclusive
+	WinnerTeamID *uint      `json:"winner_team_id"`
+	DeclaredAt   *time.Time `gorm:"type:timestamptz" json:"declared_at"`
+	DeclaredByID *uint      `json:"declared_by_id"` // NULL when declared automatically
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Relationships
+	Teams      []Team `gorm:"many2many:competition_teams" json:"teams"`
+	WinnerTeam *Team  `gorm:"foreignKey:WinnerTeamID" json:"winner_team,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Term is an academic term, points are attributed to the term their event took place in so everyone starts
+// each term from zero while keeping their lifetime total
+type Term struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"size:255;not null;unique" json:"name"`
+	StartsAt   time.Time  `gorm:"type:timestamptz;not null;index" json:"starts_at"`
+	EndsAt     time.Time  `gorm:"type:timestamptz;not null" json:"ends_at"` // Exclusive
+	ClosedAt   *time.Time `gorm:"type:timestamptz" json:"closed_at"`        // Set once the final standings were recorded
+	ClosedByID *uint      `json:"closed_by_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Contains reports whether a moment falls within the term
+func (t Term) Contains(at time.Time) bool {
+	return !at.Before(t.StartsAt) && at.Before(t.EndsAt)
+}
+
+// TermStanding is a user's final rank in a closed term, snapshotted when the term was closed
+type TermStanding struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TermID    uint      `gorm:"not null;uniqueIndex:idx_term_standings_term_user" json:"term_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_term_standings_term_user;index" json:"user_id"`
+	Rank      int       `gorm:"not null" json:"rank"` // Dense rank, users with the same points share it
+	Points    int       `gorm:"not null" json:"points"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+type BadgeSource string
+
+const (
+	BadgeSourceThreshold BadgeSource = "threshold" // Reached the award's points, revoked when the balance drops below them
+	BadgeSourceCriteria  BadgeSource = "criteria"  // Met the award's criteria, revoked when they no longer do
+	BadgeSourceEvent     BadgeSource = "event"     // Attended an event the award is attached to, revoked with the attendance
+)
+
+// UserBadge is the join table between users and the awards they earned, it keeps track of when
+// and how an award was granted (set up with SetupJoinTable in main.go)
+type UserBadge struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	AwardID   uint      `gorm:"primaryKey" json:"award_id"`
+	Source    string    `gorm:"size:20;not null;default:'threshold'" json:"source"` // Existing badges were all granted by threshold
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/open-cmuq/passport-backend/rules"
+)
+
+// UserStreak is a user's run of consecutive weeks (ISO weeks, from Monday UTC) with at least one attendance.
+// It is kept up to date as attendance changes so it never has to be worked out from a user's whole history.
+type UserStreak struct {
+	UserID       uint       `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	CurrentWeeks int        `gorm:"not null" json:"-"` // Length of the run ending at LastWeek, see Current
+	CurrentStart *time.Time `gorm:"type:timestamptz" json:"current_start"`
+	LastWeek     *time.Time `gorm:"type:timestamptz" json:"last_week"` // Monday of the latest week attended
+	LongestWeeks int        `gorm:"not null;index" json:"longest_weeks"`
+	LongestStart *time.Time `gorm:"type:timestamptz" json:"longest_start"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Current returns the length of the streak as of now. A streak stays alive through the week after its last
+// attendance, so nobody loses it before they had a chance to attend this week.
+func (s UserStreak) Current(now time.Time) int {
+	if s.LastWeek == nil || s.LastWeek.Before(rules.WeekStart(now).AddDate(0, 0, -7)) {
+		return 0
+	}
+	return s.CurrentWeeks
+}
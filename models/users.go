@@ -28,11 +28,13 @@ type User struct {
 	Email            string         `gorm:"size:255;unique;not null" json:"email"`
 	Password         string         `gorm:"size:255" json:"-"` // Exclude password from JSON
   PhotoURL         string         `gorm:"size:512" json:"photo_url"`
+	PhotoThumbnailURL string       `gorm:"size:512" json:"photo_thumbnail_url"` // Set when the photo was uploaded
 	GoogleID         string         `gorm:"size:255" json:"-"` // Exclude Google ID from JSON
   RefreshToken     string         `gorm:"size:512" json:"-"` // Refresh token
+	CalendarToken    string         `gorm:"size:64;index" json:"-"` // Secret for the personal iCalendar feed URLs
 	RefreshTokenExp  time.Time      `json:"-"`                // Refresh token expiration time
 	GradYear         int            `gorm:"not null" json:"grad_year"`
-	CurrentPoints    int            `gorm:"default:0" json:"current_points"`
+	CurrentPoints    int            `gorm:"default:0;<-:create" json:"current_points"` // Cache of the points ledger, only written by services.PostPoints
 	AwardsEarned     []Award        `gorm:"many2many:user_badges" json:"badges"` // Many-to-many relationship
 	RegistrationDate time.Time      `gorm:"not null" json:"registration_date"`
 	Status           string         `gorm:"size:50;check:status IN ('active', 'inactive', 'banned');default:'active'" json:"status"`
@@ -40,6 +42,7 @@ type User struct {
 	Department       string         `gorm:"size:255" json:"department"`
 	Title            string         `gorm:"size:255" json:"title"`
 	Biography        string         `gorm:"type:text" json:"biography"`
+	LeaderboardOptOut bool          `gorm:"default:false" json:"leaderboard_opt_out"` // Hidden from leaderboards
 	OTP              string         `gorm:"size:6" json:"-"` // OTP for email verification
 	OTPExpiresAt     time.Time      `json:"-"`              // OTP expiration time
 	CreatedAt        time.Time      `json:"created_at"`
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// Venue is a known place events are held at
+type Venue struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Name               string     `gorm:"size:255;not null;unique" json:"name"`
+	Building           string     `gorm:"size:255" json:"building"`
+	Latitude           *float64   `json:"latitude"`
+	Longitude          *float64   `json:"longitude"`
+	Capacity           *int       `json:"capacity"` // Events at the venue can't take more RSVPs than this
+	AccessibilityNotes string     `gorm:"type:text" json:"accessibility_notes"`
+	Aliases            StringList `json:"aliases"` // Other names people use for the venue, used to match free-text locations
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,282 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Page sizes used when PAGINATION_DEFAULT_PAGE_SIZE or PAGINATION_MAX_PAGE_SIZE are not set
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var (
+	ErrInvalidLimit  = errors.New("limit must be a positive integer")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Kind is the type of the values a list is ordered by, it decides how cursor values are decoded
+type Kind int
+
+const (
+	KindInt Kind = iota
+	KindFloat
+	KindString
+	KindTime
+)
+
+// Key describes how a list is ordered. The row ID always breaks ties so the order is total,
+// which is what makes keyset cursors stable while rows are inserted or deleted.
+type Key[T any] struct {
+	Column   string              // SQL expression the list is ordered by, empty to order by ID only
+	Vars     []interface{}       // Bind variables used by Column
+	Kind     Kind                // Type of the Column values
+	IDColumn string              // SQL expression of the unique row ID
+	Desc     bool                // Largest values first
+	Value    func(T) interface{} // Extracts the Column value of a row
+	ID       func(T) uint        // Extracts the ID of a row
+}
+
+// cursor is the decoded form of the opaque cursor strings handed to clients
+type cursor struct {
+	Value    interface{} `json:"v"`
+	ID       uint        `json:"id"`
+	Backward bool        `json:"b,omitempty"` // Page before the row instead of after it
+}
+
+// Meta describes where a page sits in the list
+type Meta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Next       string `json:"next,omitempty"` // Link to the next page, relative to the API root
+	Prev       string `json:"prev,omitempty"` // Link to the previous page, relative to the API root
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// Page is the response body of every paginated list endpoint
+type Page[T any] struct {
+	Data       []T  `json:"data"`
+	Pagination Meta `json:"pagination"`
+}
+
+// Paginate fetches one page of query ordered by key, reading the "limit", "cursor" and
+// "include_total" query parameters. Scopes (typically preloads) are only applied to the
+// page fetch and not to the total count.
+func Paginate[T any](c *gin.Context, query *gorm.DB, key Key[T], scopes ...func(*gorm.DB) *gorm.DB) (*Page[T], error) {
+	limit, err := parseLimit(c.Query("limit"))
+	if err != nil {
+		return nil, err
+	}
+
+	var after *cursor
+	if raw := c.Query("cursor"); raw != "" {
+		if after, err = decodeCursor(raw, key.Kind); err != nil {
+			return nil, err
+		}
+	}
+
+	page := &Page[T]{Data: []T{}, Pagination: Meta{Limit: limit}}
+
+	// The total ignores the cursor so it stays the same on every page
+	if c.Query("include_total") == "true" {
+		countQuery := query.Session(&gorm.Session{})
+		if countQuery.Statement.Model == nil && countQuery.Statement.Table == "" {
+			countQuery = countQuery.Model(new(T))
+		}
+		var total int64
+		if err := countQuery.Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Pagination.Total = &total
+	}
+
+	backward := after != nil && after.Backward
+	fetch := query.Session(&gorm.Session{}).Scopes(scopes...)
+	if after != nil {
+		fetch = fetch.Where(key.seek(after, backward))
+	}
+	fetch = key.order(fetch, backward)
+
+	// Fetch one extra row to know whether there is another page
+	var rows []T
+	if err := fetch.Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	page.Data = append(page.Data, rows...)
+
+	// Going backwards there is always a next page (the one we came from), going forwards there
+	// is always a previous page once a cursor was used
+	hasNext, hasPrev := hasMore, after != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+	if len(rows) > 0 {
+		if hasNext {
+			page.Pagination.NextCursor = key.encode(rows[len(rows)-1], false)
+			page.Pagination.Next = link(c, page.Pagination.NextCursor, limit)
+		}
+		if hasPrev {
+			page.Pagination.PrevCursor = key.encode(rows[0], true)
+			page.Pagination.Prev = link(c, page.Pagination.PrevCursor, limit)
+		}
+	}
+
+	return page, nil
+}
+
+// Requested reports whether the client asked for a page by sending "cursor", "include_total" or "paginate".
+// "limit" alone doesn't count, list endpoints that predate pagination already accepted it.
+func Requested(c *gin.Context) bool {
+	return c.Query("cursor") != "" || c.Query("include_total") != "" || c.Query("paginate") != ""
+}
+
+// PaginateIfRequested paginates like Paginate when the client asks for a page, otherwise it returns every row (or
+// the first "limit" rows) in the same order as a bare array. List endpoints that predate pagination use it so
+// clients that don't page keep getting the response they always got.
+func PaginateIfRequested[T any](c *gin.Context, query *gorm.DB, key Key[T], scopes ...func(*gorm.DB) *gorm.DB) (interface{}, error) {
+	if Requested(c) {
+		return Paginate(c, query, key, scopes...)
+	}
+
+	fetch := key.order(query.Session(&gorm.Session{}).Scopes(scopes...), false)
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return nil, ErrInvalidLimit
+		}
+		fetch = fetch.Limit(limit)
+	}
+
+	rows := []T{}
+	if err := fetch.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// seek builds the keyset condition selecting the rows after (or before) the cursor
+func (k Key[T]) seek(after *cursor, backward bool) clause.Expr {
+	op := ">"
+	if k.Desc != backward {
+		op = "<"
+	}
+
+	if k.Column == "" {
+		return clause.Expr{SQL: k.IDColumn + " " + op + " ?", Vars: []interface{}{after.ID}}
+	}
+
+	vars := append([]interface{}{}, k.Vars...)
+	vars = append(vars, after.Value)
+	vars = append(vars, k.Vars...)
+	vars = append(vars, after.Value, after.ID)
+	return clause.Expr{
+		SQL:  "(" + k.Column + " " + op + " ? OR (" + k.Column + " = ? AND " + k.IDColumn + " " + op + " ?))",
+		Vars: vars,
+	}
+}
+
+// order applies the ordering of the key, reversed when paging backwards
+func (k Key[T]) order(db *gorm.DB, backward bool) *gorm.DB {
+	direction := " ASC"
+	if k.Desc != backward {
+		direction = " DESC"
+	}
+
+	if k.Column != "" {
+		db = db.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                k.Column + direction,
+			Vars:               k.Vars,
+			WithoutParentheses: true,
+		}})
+	}
+	return db.Order(k.IDColumn + direction)
+}
+
+// encode builds the opaque cursor pointing at a row
+func (k Key[T]) encode(row T, backward bool) string {
+	c := cursor{ID: k.ID(row), Backward: backward}
+	if k.Column != "" {
+		c.Value = k.Value(row)
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string, kind Kind) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// JSON loses the Go types of the values, restore them so they bind correctly
+	switch value := c.Value.(type) {
+	case nil:
+	case float64:
+		if kind == KindInt {
+			c.Value = int64(value)
+		}
+	case string:
+		if kind == KindTime {
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			c.Value = t
+		}
+	default:
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+func parseLimit(raw string) (int, error) {
+	max := envInt("PAGINATION_MAX_PAGE_SIZE", maxPageSize)
+	if raw == "" {
+		return min(envInt("PAGINATION_DEFAULT_PAGE_SIZE", defaultPageSize), max), nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, ErrInvalidLimit
+	}
+	return min(limit, max), nil
+}
+
+// link rebuilds the current request URL pointing at another cursor
+func link(c *gin.Context, cur string, limit int) string {
+	query := c.Request.URL.Query()
+	query.Set("cursor", cur)
+	query.Set("limit", strconv.Itoa(limit))
+	return c.Request.URL.Path + "?" + query.Encode()
+}
+
+func envInt(name string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
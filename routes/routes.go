@@ -1,9 +1,12 @@
 package routes
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/open-cmuq/passport-backend/controllers"
 	"github.com/open-cmuq/passport-backend/middleware"
+	"github.com/open-cmuq/passport-backend/storage"
 )
 
 func SetupRoutes(router *gin.Engine) {
@@ -23,6 +26,12 @@ func SetupRoutes(router *gin.Engine) {
 		// userRoutes.POST("/", controllers.CreateUser)
 		userRoutes.GET("/:id", controllers.GetUserByID)
 		userRoutes.PATCH("/:id", middleware.OwnershipMiddleware(), controllers.UpdateUser)
+		userRoutes.POST("/:id/photo", middleware.OwnershipMiddleware(), controllers.UploadUserPhoto)
+		userRoutes.GET("/:id/points", middleware.OwnershipMiddleware(), controllers.GetUserPoints)
+		userRoutes.GET("/:id/points/history", middleware.OwnershipMiddleware(), controllers.GetUserPointsHistory)
+		userRoutes.GET("/:id/redemptions", middleware.OwnershipMiddleware(), controllers.GetUserRedemptions)
+		userRoutes.GET("/:id/streak", middleware.OwnershipMiddleware(), controllers.GetUserStreak)
+		userRoutes.GET("/:id/teams", controllers.GetUserTeams)
 		userRoutes.DELETE("/:id", middleware.AdminOnlyMiddleware(), controllers.DeleteUser)
 	}
 
@@ -33,11 +42,183 @@ func SetupRoutes(router *gin.Engine) {
 	{
 		eventRoutes.GET("/", controllers.GetEvents)
 		eventRoutes.POST("/", controllers.CreateEvent)
+		eventRoutes.GET("/deleted", middleware.AdminOnlyMiddleware(), controllers.GetDeletedEvents)
 		eventRoutes.GET("/:eventId", controllers.GetEvent)
 		eventRoutes.PATCH("/:eventId", controllers.UpdateEvent)
 		eventRoutes.DELETE("/:eventId", controllers.DeleteEvent)
+		eventRoutes.POST("/:eventId/restore", controllers.RestoreEvent)
+		eventRoutes.POST("/:eventId/clone", controllers.CloneEvent)
+		eventRoutes.PATCH("/:eventId/status", controllers.UpdateEventStatus)
+		eventRoutes.POST("/:eventId/image", controllers.UploadEventImage)
+		eventRoutes.GET("/:eventId/ics", controllers.GetEventICS)
 		eventRoutes.GET("/:eventId/attendees", controllers.GetEventAttendees)
 		eventRoutes.POST("/:eventId/attendances", controllers.AddAttendances)
 		eventRoutes.DELETE("/:eventId/attendances", controllers.DeleteAttendances)
+		eventRoutes.GET("/:eventId/rsvps", controllers.GetEventRSVPs)
+		eventRoutes.GET("/:eventId/rsvps/stats", controllers.GetEventRSVPStats)
+		eventRoutes.GET("/:eventId/rsvp", controllers.GetMyRSVP)
+		eventRoutes.POST("/:eventId/rsvp", controllers.CreateRSVP)
+		eventRoutes.DELETE("/:eventId/rsvp", controllers.CancelRSVP)
+		eventRoutes.GET("/:eventId/join", controllers.GetEventJoinLink)
+		eventRoutes.GET("/:eventId/survey", controllers.GetEventSurvey)
+		eventRoutes.POST("/:eventId/survey", controllers.CreateEventSurvey)
+		eventRoutes.PATCH("/:eventId/survey", controllers.UpdateEventSurvey)
+		eventRoutes.DELETE("/:eventId/survey", controllers.DeleteEventSurvey)
+		eventRoutes.POST("/:eventId/survey/responses", controllers.SubmitSurveyResponse)
+		eventRoutes.GET("/:eventId/survey/results", controllers.GetSurveyResults)
+	}
+
+	// Academic term routes
+	termRoutes := router.Group("/terms")
+	termRoutes.Use(middleware.AuthMiddleware())
+	{
+		termRoutes.GET("/", controllers.GetTerms)
+		termRoutes.GET("/current", controllers.GetCurrentTerm)
+		termRoutes.GET("/:termId", controllers.GetTerm)
+		termRoutes.GET("/:termId/standings", controllers.GetTermStandings)
+		termRoutes.POST("/", middleware.AdminOnlyMiddleware(), controllers.CreateTerm)
+		termRoutes.PATCH("/:termId", middleware.AdminOnlyMiddleware(), controllers.UpdateTerm)
+		termRoutes.DELETE("/:termId", middleware.AdminOnlyMiddleware(), controllers.DeleteTerm)
+		termRoutes.POST("/:termId/close", middleware.AdminOnlyMiddleware(), controllers.CloseTerm)
+	}
+
+	// Rewards catalog routes
+	rewardRoutes := router.Group("/rewards")
+	rewardRoutes.Use(middleware.AuthMiddleware())
+	{
+		rewardRoutes.GET("/", controllers.GetRewards)
+		rewardRoutes.GET("/:rewardId", controllers.GetReward)
+		rewardRoutes.POST("/", middleware.AdminOnlyMiddleware(), controllers.CreateReward)
+		rewardRoutes.PATCH("/:rewardId", middleware.AdminOnlyMiddleware(), controllers.UpdateReward)
+		rewardRoutes.POST("/:rewardId/redeem", controllers.RedeemReward)
+	}
+
+	// Redemption routes, staff hand rewards over by pickup code
+	redemptionRoutes := router.Group("/redemptions")
+	redemptionRoutes.Use(middleware.AuthMiddleware())
+	{
+		redemptionRoutes.GET("/", middleware.StaffOnlyMiddleware(), controllers.GetRedemptions)
+		redemptionRoutes.GET("/code/:code", middleware.StaffOnlyMiddleware(), controllers.GetRedemptionByCode)
+		redemptionRoutes.POST("/fulfill", middleware.StaffOnlyMiddleware(), controllers.FulfillRedemption)
+		redemptionRoutes.POST("/:redemptionId/cancel", controllers.CancelRedemption)
+	}
+
+	// Team routes, houses are assigned by admins and users join clubs themselves
+	teamRoutes := router.Group("/teams")
+	teamRoutes.Use(middleware.AuthMiddleware())
+	{
+		teamRoutes.GET("/", controllers.GetTeams)
+		teamRoutes.GET("/leaderboard", controllers.GetTeamLeaderboard)
+		teamRoutes.GET("/:teamId", controllers.GetTeam)
+		teamRoutes.GET("/:teamId/members", controllers.GetTeamMembers)
+		teamRoutes.POST("/:teamId/members", controllers.AddTeamMember)
+		teamRoutes.DELETE("/:teamId/members/:userId", controllers.RemoveTeamMember)
+		teamRoutes.POST("/", middleware.AdminOnlyMiddleware(), controllers.CreateTeam)
+		teamRoutes.PATCH("/:teamId", middleware.AdminOnlyMiddleware(), controllers.UpdateTeam)
+		teamRoutes.DELETE("/:teamId", middleware.AdminOnlyMiddleware(), controllers.DeleteTeam)
+	}
+
+	// Time-boxed competitions between teams
+	competitionRoutes := router.Group("/competitions")
+	competitionRoutes.Use(middleware.AuthMiddleware())
+	{
+		competitionRoutes.GET("/", controllers.GetCompetitions)
+		competitionRoutes.GET("/:competitionId", controllers.GetCompetition)
+		competitionRoutes.POST("/", middleware.AdminOnlyMiddleware(), controllers.CreateCompetition)
+		competitionRoutes.PATCH("/:competitionId", middleware.AdminOnlyMiddleware(), controllers.UpdateCompetition)
+		competitionRoutes.DELETE("/:competitionId", middleware.AdminOnlyMiddleware(), controllers.DeleteCompetition)
+		competitionRoutes.POST("/:competitionId/declare", middleware.AdminOnlyMiddleware(), controllers.DeclareCompetitionWinner)
+	}
+
+	// Leaderboard of users by points
+	router.GET("/leaderboard", middleware.AuthMiddleware(), controllers.GetLeaderboard)
+
+	// Points reconciliation routes
+	pointRoutes := router.Group("/points")
+	pointRoutes.Use(middleware.AuthMiddleware(), middleware.AdminOnlyMiddleware())
+	{
+		pointRoutes.POST("/reconcile", controllers.ReconcilePoints)
+		pointRoutes.GET("/reconciliations", controllers.GetReconciliationRuns)
+		pointRoutes.GET("/reconciliations/:runId", controllers.GetReconciliationRun)
+	}
+
+	// Tracked join links of online events, authenticated by the secret token in their URL
+	router.GET("/join/:token", controllers.FollowJoinLink)
+
+	// Attendance webhooks of meeting platforms, authenticated by each provider's signature
+	router.POST("/webhooks/meetings/:provider", controllers.MeetingWebhook)
+
+	// Award routes
+	awardRoutes := router.Group("/awards")
+	awardRoutes.Use(middleware.AuthMiddleware())
+	{
+		awardRoutes.GET("/", controllers.GetAwards)
+		awardRoutes.GET("/:awardId", controllers.GetAward)
+		awardRoutes.POST("/", middleware.AdminOnlyMiddleware(), controllers.CreateAward)
+		awardRoutes.POST("/dry-run", middleware.AdminOnlyMiddleware(), controllers.DryRunAward)
+		awardRoutes.PATCH("/:awardId", middleware.AdminOnlyMiddleware(), controllers.UpdateAward)
+		awardRoutes.DELETE("/:awardId", middleware.AdminOnlyMiddleware(), controllers.DeleteAward)
+		awardRoutes.POST("/:awardId/icon", middleware.AdminOnlyMiddleware(), controllers.UploadAwardIcon)
+	}
+
+	// Uploaded files are only served by us when they are stored on the local filesystem
+	if local, ok := storage.Store.(*storage.LocalStore); ok && strings.HasPrefix(local.BaseURL, "/") {
+		router.Static(local.BaseURL, local.Dir)
+	}
+
+	// Event template routes
+	templateRoutes := router.Group("/templates")
+	templateRoutes.Use(middleware.AuthMiddleware())
+	{
+		templateRoutes.GET("/", controllers.GetTemplates)
+		templateRoutes.POST("/", controllers.CreateTemplate)
+		templateRoutes.GET("/:templateId", controllers.GetTemplate)
+		templateRoutes.PATCH("/:templateId", controllers.UpdateTemplate)
+		templateRoutes.DELETE("/:templateId", controllers.DeleteTemplate)
+	}
+
+	// Venue routes
+	venueRoutes := router.Group("/venues")
+	venueRoutes.Use(middleware.AuthMiddleware())
+	{
+		venueRoutes.GET("/", controllers.GetVenues)
+		venueRoutes.POST("/", middleware.AdminOnlyMiddleware(), controllers.CreateVenue)
+		venueRoutes.POST("/map-locations", middleware.AdminOnlyMiddleware(), controllers.MapVenueLocations)
+		venueRoutes.GET("/:venueId", controllers.GetVenue)
+		venueRoutes.PATCH("/:venueId", middleware.AdminOnlyMiddleware(), controllers.UpdateVenue)
+		venueRoutes.DELETE("/:venueId", middleware.AdminOnlyMiddleware(), controllers.DeleteVenue)
+	}
+
+	// Category and tag routes
+	categoryRoutes := router.Group("/categories")
+	categoryRoutes.Use(middleware.AuthMiddleware())
+	{
+		categoryRoutes.GET("/", controllers.GetCategories)
+		categoryRoutes.POST("/", middleware.AdminOnlyMiddleware(), controllers.CreateCategory)
+		categoryRoutes.PATCH("/:categoryId", middleware.AdminOnlyMiddleware(), controllers.UpdateCategory)
+		categoryRoutes.DELETE("/:categoryId", middleware.AdminOnlyMiddleware(), controllers.DeleteCategory)
+	}
+	router.GET("/tags", middleware.AuthMiddleware(), controllers.GetTags)
+
+	// iCalendar feeds, the feeds themselves are authenticated by the secret token in their URL
+	calendarRoutes := router.Group("/calendar")
+	{
+		calendarRoutes.GET("/feeds", middleware.AuthMiddleware(), controllers.GetCalendarFeeds)
+		calendarRoutes.POST("/feeds/rotate", middleware.AuthMiddleware(), controllers.RotateCalendarToken)
+		calendarRoutes.GET("/:token/events.ics", controllers.GetEventsFeed)
+		calendarRoutes.GET("/:token/my-events.ics", controllers.GetPersonalFeed)
+	}
+
+	// Recurring event series routes
+	seriesRoutes := router.Group("/series")
+	seriesRoutes.Use(middleware.AuthMiddleware())
+	{
+		seriesRoutes.GET("/", controllers.GetSeriesList)
+		seriesRoutes.POST("/", controllers.CreateSeries)
+		seriesRoutes.GET("/:seriesId", controllers.GetSeries)
+		seriesRoutes.GET("/:seriesId/stats", controllers.GetSeriesStats)
+		seriesRoutes.PATCH("/:seriesId/events/:eventId", controllers.UpdateSeriesOccurrence)
+		seriesRoutes.POST("/:seriesId/exceptions", controllers.AddSeriesException)
+		seriesRoutes.DELETE("/:seriesId/exceptions/:exceptionId", controllers.DeleteSeriesException)
 	}
 }
@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Rule types, "all" and "any" combine other rules
+const (
+	RuleAll            = "all"
+	RuleAny            = "any"
+	RuleAttendCount    = "attend_count"    // Attended at least Count events
+	RuleAttendEvents   = "attend_events"   // Attended the events in EventIDs, at least Count of them when set
+	RuleAttendCategory = "attend_category" // Attended at least Count events of CategoryID
+	RulePointsInTerm   = "points_in_term"  // Earned at least Points between Start and End
+	RuleWeeklyStreak   = "weekly_streak"   // Attended an event in Weeks consecutive weeks
+)
+
+// Rules are nested at most this deep, which is plenty for any sensible award
+const maxRuleDepth = 5
+
+// Criteria is a declarative rule deciding who earns an award, stored as JSON on the award. For example
+// {"type": "all", "rules": [{"type": "attend_count", "count": 5}, {"type": "weekly_streak", "weeks": 3}]}
+type Criteria struct {
+	Type       string     `json:"type"`
+	Count      int        `json:"count,omitempty"`
+	EventIDs   []uint     `json:"event_ids,omitempty"`
+	CategoryID uint       `json:"category_id,omitempty"`
+	Points     int        `json:"points,omitempty"`
+	Start      *time.Time `json:"start,omitempty"`
+	End        *time.Time `json:"end,omitempty"`
+	Weeks      int        `json:"weeks,omitempty"`
+	Rules      []Criteria `json:"rules,omitempty"`
+}
+
+// Validate checks the criteria can be evaluated, the error says which rule is wrong
+func (c *Criteria) Validate() error {
+	return c.validate(1)
+}
+
+func (c *Criteria) validate(depth int) error {
+	if depth > maxRuleDepth {
+		return fmt.Errorf("rules can be nested at most %d levels deep", maxRuleDepth)
+	}
+
+	switch c.Type {
+	case RuleAll, RuleAny:
+		if len(c.Rules) == 0 {
+			return fmt.Errorf("%s needs at least one rule", c.Type)
+		}
+		for i := range c.Rules {
+			if err := c.Rules[i].validate(depth + 1); err != nil {
+				return err
+			}
+		}
+	case RuleAttendCount:
+		if c.Count <= 0 {
+			return errors.New("attend_count needs a positive count")
+		}
+	case RuleAttendEvents:
+		if len(c.EventIDs) == 0 {
+			return errors.New("attend_events needs event_ids")
+		}
+		if c.Count < 0 || c.Count > len(c.EventIDs) {
+			return errors.New("attend_events count can't exceed the number of event_ids")
+		}
+	case RuleAttendCategory:
+		if c.CategoryID == 0 || c.Count <= 0 {
+			return errors.New("attend_category needs a category_id and a positive count")
+		}
+	case RulePointsInTerm:
+		if c.Points <= 0 || c.Start == nil || c.End == nil {
+			return errors.New("points_in_term needs positive points, a start and an end")
+		}
+		if !c.Start.Before(*c.End) {
+			return errors.New("points_in_term start must be before its end")
+		}
+	case RuleWeeklyStreak:
+		if c.Weeks <= 0 {
+			return errors.New("weekly_streak needs a positive number of weeks")
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", c.Type)
+	}
+	return nil
+}
+
+// NeedsPoints reports whether evaluating the criteria needs the user's ledger, which is only loaded when it does
+func (c *Criteria) NeedsPoints() bool {
+	return c.uses(RulePointsInTerm)
+}
+
+// NeedsAttendances reports whether evaluating the criteria needs the user's attendance history, which is only
+// loaded when it does. Streaks come from the user's stored streak instead.
+func (c *Criteria) NeedsAttendances() bool {
+	return c.uses(RuleAttendCount, RuleAttendEvents, RuleAttendCategory)
+}
+
+// uses reports whether the criteria contain a rule of one of the types
+func (c *Criteria) uses(types ...string) bool {
+	for _, ruleType := range types {
+		if c.Type == ruleType {
+			return true
+		}
+	}
+	for i := range c.Rules {
+		if c.Rules[i].uses(types...) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Criteria) Value() (driver.Value, error) {
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+func (c *Criteria) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return errors.New("unsupported type for Criteria")
+	}
+}
+
+func (Criteria) GormDataType() string {
+	return "jsonb"
+}
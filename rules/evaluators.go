@@ -0,0 +1,148 @@
+package rules
+
+import (
+	"time"
+)
+
+// Attendance is an event a user attended
+type Attendance struct {
+	EventID    uint
+	CategoryID *uint
+	At         time.Time // When the attendance was recorded
+}
+
+// PointEntry is a change to a user's balance
+type PointEntry struct {
+	Delta int
+	At    time.Time
+}
+
+// Facts is everything the rules look at for one user, evaluating rules never touches the database
+type Facts struct {
+	Attendances   []Attendance // Only loaded when the criteria need it, see Criteria.NeedsAttendances
+	Points        []PointEntry // Only loaded when the criteria need it, see Criteria.NeedsPoints
+	LongestStreak int          // Most consecutive weeks with an attendance, counted like LongestWeeklyStreak
+}
+
+// evaluator decides whether the facts satisfy one rule
+type evaluator func(c *Criteria, facts *Facts) bool
+
+// evaluators holds the evaluator of every rule type, filled in init because "all" and "any" refer back to it
+var evaluators map[string]evaluator
+
+func init() {
+	evaluators = map[string]evaluator{
+		RuleAll:            evaluateAll,
+		RuleAny:            evaluateAny,
+		RuleAttendCount:    evaluateAttendCount,
+		RuleAttendEvents:   evaluateAttendEvents,
+		RuleAttendCategory: evaluateAttendCategory,
+		RulePointsInTerm:   evaluatePointsInTerm,
+		RuleWeeklyStreak:   evaluateWeeklyStreak,
+	}
+}
+
+// Evaluate reports whether the facts satisfy the criteria, unknown rules are never satisfied
+func (c *Criteria) Evaluate(facts *Facts) bool {
+	evaluate, ok := evaluators[c.Type]
+	return ok && evaluate(c, facts)
+}
+
+func evaluateAll(c *Criteria, facts *Facts) bool {
+	for i := range c.Rules {
+		if !c.Rules[i].Evaluate(facts) {
+			return false
+		}
+	}
+	return true
+}
+
+func evaluateAny(c *Criteria, facts *Facts) bool {
+	for i := range c.Rules {
+		if c.Rules[i].Evaluate(facts) {
+			return true
+		}
+	}
+	return false
+}
+
+func evaluateAttendCount(c *Criteria, facts *Facts) bool {
+	return len(attendedEvents(facts)) >= c.Count
+}
+
+func evaluateAttendEvents(c *Criteria, facts *Facts) bool {
+	attended := attendedEvents(facts)
+	matched := 0
+	for _, eventID := range c.EventIDs {
+		if attended[eventID] {
+			matched++
+		}
+	}
+	if c.Count > 0 {
+		return matched >= c.Count
+	}
+	return matched == len(c.EventIDs)
+}
+
+func evaluateAttendCategory(c *Criteria, facts *Facts) bool {
+	events := make(map[uint]bool)
+	for _, attendance := range facts.Attendances {
+		if attendance.CategoryID != nil && *attendance.CategoryID == c.CategoryID {
+			events[attendance.EventID] = true
+		}
+	}
+	return len(events) >= c.Count
+}
+
+func evaluatePointsInTerm(c *Criteria, facts *Facts) bool {
+	total := 0
+	for _, entry := range facts.Points {
+		if !entry.At.Before(*c.Start) && entry.At.Before(*c.End) {
+			total += entry.Delta
+		}
+	}
+	return total >= c.Points
+}
+
+func evaluateWeeklyStreak(c *Criteria, facts *Facts) bool {
+	return facts.LongestStreak >= c.Weeks
+}
+
+// LongestWeeklyStreak returns the largest number of consecutive ISO weeks with at least one attendance, which
+// is how stored streaks are counted
+func LongestWeeklyStreak(attendances []Attendance) int {
+	weeks := make(map[time.Time]bool)
+	for _, attendance := range attendances {
+		weeks[WeekStart(attendance.At)] = true
+	}
+
+	longest := 0
+	for week := range weeks {
+		// Only count from the first week of each streak
+		if weeks[week.AddDate(0, 0, -7)] {
+			continue
+		}
+		length := 1
+		for weeks[week.AddDate(0, 0, 7*length)] {
+			length++
+		}
+		longest = max(longest, length)
+	}
+	return longest
+}
+
+// WeekStart returns midnight UTC of the Monday starting the ISO week of t
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7 // Days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// attendedEvents returns the distinct events in the facts
+func attendedEvents(facts *Facts) map[uint]bool {
+	events := make(map[uint]bool, len(facts.Attendances))
+	for _, attendance := range facts.Attendances {
+		events[attendance.EventID] = true
+	}
+	return events
+}
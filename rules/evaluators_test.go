@@ -0,0 +1,242 @@
+package rules
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func uintPtr(v uint) *uint { return &v }
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func TestValidate(t *testing.T) {
+	start, end := date("2025-01-01T00:00:00Z"), date("2025-05-01T00:00:00Z")
+	deep := Criteria{Type: RuleAttendCount, Count: 1}
+	for i := 0; i < maxRuleDepth; i++ {
+		deep = Criteria{Type: RuleAll, Rules: []Criteria{deep}}
+	}
+
+	tests := []struct {
+		name     string
+		criteria Criteria
+		valid    bool
+	}{
+		{"attend_count", Criteria{Type: RuleAttendCount, Count: 3}, true},
+		{"attend_count without count", Criteria{Type: RuleAttendCount}, false},
+		{"attend_events", Criteria{Type: RuleAttendEvents, EventIDs: []uint{1, 2}}, true},
+		{"attend_events some of them", Criteria{Type: RuleAttendEvents, EventIDs: []uint{1, 2}, Count: 1}, true},
+		{"attend_events without events", Criteria{Type: RuleAttendEvents}, false},
+		{"attend_events count above events", Criteria{Type: RuleAttendEvents, EventIDs: []uint{1}, Count: 2}, false},
+		{"attend_events negative count", Criteria{Type: RuleAttendEvents, EventIDs: []uint{1}, Count: -1}, false},
+		{"attend_category", Criteria{Type: RuleAttendCategory, CategoryID: 4, Count: 2}, true},
+		{"attend_category without category", Criteria{Type: RuleAttendCategory, Count: 2}, false},
+		{"attend_category without count", Criteria{Type: RuleAttendCategory, CategoryID: 4}, false},
+		{"points_in_term", Criteria{Type: RulePointsInTerm, Points: 10, Start: &start, End: &end}, true},
+		{"points_in_term without end", Criteria{Type: RulePointsInTerm, Points: 10, Start: &start}, false},
+		{"points_in_term without points", Criteria{Type: RulePointsInTerm, Start: &start, End: &end}, false},
+		{"points_in_term reversed", Criteria{Type: RulePointsInTerm, Points: 10, Start: &end, End: &start}, false},
+		{"weekly_streak", Criteria{Type: RuleWeeklyStreak, Weeks: 3}, true},
+		{"weekly_streak without weeks", Criteria{Type: RuleWeeklyStreak}, false},
+		{"unknown type", Criteria{Type: "attend_everything"}, false},
+		{"empty all", Criteria{Type: RuleAll}, false},
+		{"empty any", Criteria{Type: RuleAny}, false},
+		{"nested", Criteria{Type: RuleAny, Rules: []Criteria{
+			{Type: RuleAttendCount, Count: 5},
+			{Type: RuleAll, Rules: []Criteria{{Type: RuleWeeklyStreak, Weeks: 2}, {Type: RuleAttendCategory, CategoryID: 1, Count: 1}}},
+		}}, true},
+		{"nested invalid rule", Criteria{Type: RuleAll, Rules: []Criteria{{Type: RuleAttendCount, Count: 1}, {Type: RuleWeeklyStreak}}}, false},
+		{"nested too deep", deep, false},
+		{"nested at the limit", deep.Rules[0], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.criteria.Validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	start, end := date("2025-01-01T00:00:00Z"), date("2025-05-01T00:00:00Z")
+	facts := &Facts{
+		Attendances: []Attendance{
+			{EventID: 1, CategoryID: uintPtr(7), At: date("2025-01-06T10:00:00Z")},
+			{EventID: 1, CategoryID: uintPtr(7), At: date("2025-01-06T11:00:00Z")}, // Scanned twice
+			{EventID: 2, CategoryID: uintPtr(7), At: date("2025-01-14T10:00:00Z")},
+			{EventID: 3, At: date("2025-01-22T10:00:00Z")},
+			{EventID: 4, CategoryID: uintPtr(8), At: date("2025-02-10T10:00:00Z")},
+		},
+		Points: []PointEntry{
+			{Delta: 20, At: date("2024-12-31T23:59:59Z")}, // Before the term
+			{Delta: 10, At: start},
+			{Delta: 15, At: date("2025-03-01T00:00:00Z")},
+			{Delta: -5, At: date("2025-03-02T00:00:00Z")},
+			{Delta: 30, At: end}, // The end is exclusive
+		},
+		LongestStreak: 3,
+	}
+
+	tests := []struct {
+		name     string
+		criteria Criteria
+		want     bool
+	}{
+		{"attend_count counts distinct events", Criteria{Type: RuleAttendCount, Count: 4}, true},
+		{"attend_count above attended", Criteria{Type: RuleAttendCount, Count: 5}, false},
+		{"attend_events all attended", Criteria{Type: RuleAttendEvents, EventIDs: []uint{1, 3}}, true},
+		{"attend_events one missing", Criteria{Type: RuleAttendEvents, EventIDs: []uint{1, 9}}, false},
+		{"attend_events enough of them", Criteria{Type: RuleAttendEvents, EventIDs: []uint{1, 9}, Count: 1}, true},
+		{"attend_events not enough of them", Criteria{Type: RuleAttendEvents, EventIDs: []uint{1, 8, 9}, Count: 2}, false},
+		{"attend_category", Criteria{Type: RuleAttendCategory, CategoryID: 7, Count: 2}, true},
+		{"attend_category counts distinct events", Criteria{Type: RuleAttendCategory, CategoryID: 7, Count: 3}, false},
+		{"attend_category other category", Criteria{Type: RuleAttendCategory, CategoryID: 9, Count: 1}, false},
+		{"points_in_term", Criteria{Type: RulePointsInTerm, Points: 20, Start: &start, End: &end}, true},
+		{"points_in_term bounds", Criteria{Type: RulePointsInTerm, Points: 21, Start: &start, End: &end}, false},
+		{"weekly_streak", Criteria{Type: RuleWeeklyStreak, Weeks: 3}, true},
+		{"weekly_streak too long", Criteria{Type: RuleWeeklyStreak, Weeks: 4}, false},
+		{"unknown type", Criteria{Type: "attend_everything"}, false},
+		{"all satisfied", Criteria{Type: RuleAll, Rules: []Criteria{
+			{Type: RuleAttendCount, Count: 2}, {Type: RuleWeeklyStreak, Weeks: 2},
+		}}, true},
+		{"all with one failing", Criteria{Type: RuleAll, Rules: []Criteria{
+			{Type: RuleAttendCount, Count: 2}, {Type: RuleWeeklyStreak, Weeks: 5},
+		}}, false},
+		{"any with one satisfied", Criteria{Type: RuleAny, Rules: []Criteria{
+			{Type: RuleAttendCount, Count: 10}, {Type: RuleAttendCategory, CategoryID: 8, Count: 1},
+		}}, true},
+		{"any with none satisfied", Criteria{Type: RuleAny, Rules: []Criteria{
+			{Type: RuleAttendCount, Count: 10}, {Type: RuleAttendCategory, CategoryID: 9, Count: 1},
+		}}, false},
+		{"nested", Criteria{Type: RuleAll, Rules: []Criteria{
+			{Type: RuleAny, Rules: []Criteria{{Type: RuleAttendCount, Count: 10}, {Type: RuleAttendEvents, EventIDs: []uint{4}}}},
+			{Type: RuleAny, Rules: []Criteria{{Type: RulePointsInTerm, Points: 20, Start: &start, End: &end}}},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.criteria.Evaluate(facts); got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithoutFacts(t *testing.T) {
+	criteria := Criteria{Type: RuleAny, Rules: []Criteria{
+		{Type: RuleAttendCount, Count: 1},
+		{Type: RuleWeeklyStreak, Weeks: 1},
+		{Type: RulePointsInTerm, Points: 1, Start: timePtr(date("2025-01-01T00:00:00Z")), End: timePtr(date("2026-01-01T00:00:00Z"))},
+	}}
+	if criteria.Evaluate(&Facts{}) {
+		t.Error("Evaluate() = true for a user without any facts")
+	}
+}
+
+func TestNeedsAttendances(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria Criteria
+		want     bool
+	}{
+		{"streak only", Criteria{Type: RuleWeeklyStreak, Weeks: 2}, false},
+		{"points only", Criteria{Type: RulePointsInTerm}, false},
+		{"attend_count", Criteria{Type: RuleAttendCount, Count: 1}, true},
+		{"nested attend_category", Criteria{Type: RuleAll, Rules: []Criteria{
+			{Type: RuleWeeklyStreak, Weeks: 2},
+			{Type: RuleAny, Rules: []Criteria{{Type: RuleAttendCategory, CategoryID: 1, Count: 1}}},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.criteria.NeedsAttendances(); got != tt.want {
+				t.Errorf("NeedsAttendances() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsPoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria Criteria
+		want     bool
+	}{
+		{"attendance only", Criteria{Type: RuleAttendCount, Count: 1}, false},
+		{"points_in_term", Criteria{Type: RulePointsInTerm}, true},
+		{"nested points_in_term", Criteria{Type: RuleAny, Rules: []Criteria{
+			{Type: RuleAttendCount, Count: 1},
+			{Type: RuleAll, Rules: []Criteria{{Type: RulePointsInTerm}}},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.criteria.NeedsPoints(); got != tt.want {
+				t.Errorf("NeedsPoints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	tests := []struct {
+		name string
+		at   string
+		want string
+	}{
+		{"monday midnight", "2025-01-06T00:00:00Z", "2025-01-06T00:00:00Z"},
+		{"sunday night", "2025-01-12T23:59:59Z", "2025-01-06T00:00:00Z"},
+		{"week across new year", "2025-01-01T12:00:00Z", "2024-12-30T00:00:00Z"},
+		{"ISO week 53", "2021-01-03T12:00:00Z", "2020-12-28T00:00:00Z"},
+		{"leap day", "2024-02-29T12:00:00Z", "2024-02-26T00:00:00Z"},
+		{"converted to UTC first", "2025-01-13T01:00:00+03:00", "2025-01-06T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WeekStart(date(tt.at)); !got.Equal(date(tt.want)) || got.Location() != time.UTC {
+				t.Errorf("WeekStart(%s) = %v, want %s", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLongestWeeklyStreak(t *testing.T) {
+	tests := []struct {
+		name  string
+		times []string
+		want  int
+	}{
+		{"no attendance", nil, 0},
+		{"single attendance", []string{"2025-01-08T10:00:00Z"}, 1},
+		{"same week", []string{"2025-01-06T10:00:00Z", "2025-01-12T22:00:00Z"}, 1},
+		{"consecutive weeks", []string{"2025-01-12T22:00:00Z", "2025-01-13T08:00:00Z", "2025-01-20T08:00:00Z"}, 3},
+		{"gap resets", []string{"2025-01-06T10:00:00Z", "2025-01-13T10:00:00Z", "2025-01-27T10:00:00Z"}, 2},
+		{"longest is not the latest", []string{
+			"2025-01-06T10:00:00Z", "2025-01-13T10:00:00Z", "2025-01-20T10:00:00Z",
+			"2025-03-03T10:00:00Z", "2025-03-10T10:00:00Z",
+		}, 3},
+		{"unordered", []string{"2025-01-20T10:00:00Z", "2025-01-06T10:00:00Z", "2025-01-13T10:00:00Z"}, 3},
+		{"across new year", []string{"2024-12-23T10:00:00Z", "2024-12-31T10:00:00Z", "2025-01-06T10:00:00Z"}, 3},
+		{"across ISO week 53", []string{"2020-12-24T10:00:00Z", "2021-01-01T10:00:00Z", "2021-01-04T10:00:00Z"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attendances := make([]Attendance, 0, len(tt.times))
+			for i, at := range tt.times {
+				attendances = append(attendances, Attendance{EventID: uint(i + 1), At: date(at)})
+			}
+			if got := LongestWeeklyStreak(attendances); got != tt.want {
+				t.Errorf("LongestWeeklyStreak() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AttendanceResult summarizes attendance recorded for an event
+type AttendanceResult struct {
+	NewAttendees  []uint          // Users whose attendance was recorded now
+	Duplicates    int             // Users who had already attended
+	AwardsGranted int64           // Awards the new attendees became eligible for
+	AwardsEarned  map[uint][]uint // IDs of the awards each new attendee earned
+}
+
+// Attendee is a user whose attendance is recorded along with when they were scanned (or joined online)
+type Attendee struct {
+	UserID      uint
+	ScannedTime time.Time
+}
+
+// AttendeesAt lists users who all attended at the same time
+func AttendeesAt(userIDs []uint, at time.Time) []Attendee {
+	attendees := make([]Attendee, 0, len(userIDs))
+	for _, userID := range userIDs {
+		attendees = append(attendees, Attendee{UserID: userID, ScannedTime: at})
+	}
+	return attendees
+}
+
+// RecordAttendances records that users attended an event, credits them the event's points, extends their
+// weekly streaks and grants the event's awards along with the others they became eligible for. Users who
+// already attended are skipped, as are repeats of a user after the first. It must run inside a transaction,
+// every way of taking attendance (staff, join links, meeting webhooks) goes through here. actorID is the
+// staff member who took attendance, 0 when it was recorded automatically.
+func RecordAttendances(tx *gorm.DB, event models.Event, attendees []Attendee, source models.AttendanceSource, actorID uint) (AttendanceResult, error) {
+	var result AttendanceResult
+	if len(attendees) == 0 {
+		return result, nil
+	}
+	userIDs := make([]uint, 0, len(attendees))
+	for _, attendee := range attendees {
+		userIDs = append(userIDs, attendee.UserID)
+	}
+
+	// Lock the event so concurrent recordings (e.g. a double-clicked join link) can't both insert
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Event{}, event.ID).Error; err != nil {
+		return result, err
+	}
+
+	// Find existing attendances to avoid duplicates
+	var existingUserIDs []uint
+	if err := tx.Model(&models.Attendance{}).
+		Where("user_id IN ? AND event_id = ?", userIDs, event.ID).
+		Pluck("user_id", &existingUserIDs).Error; err != nil {
+		return result, err
+	}
+	seen := make(map[uint]bool, len(userIDs))
+	for _, userID := range existingUserIDs {
+		seen[userID] = true
+	}
+
+	var newAttendances []models.Attendance
+	for _, attendee := range attendees {
+		if seen[attendee.UserID] {
+			result.Duplicates++
+			continue
+		}
+		seen[attendee.UserID] = true
+		newAttendances = append(newAttendances, models.Attendance{
+			UserID:      attendee.UserID,
+			EventID:     event.ID,
+			ScannedTime: attendee.ScannedTime,
+			Source:      string(source),
+		})
+		result.NewAttendees = append(result.NewAttendees, attendee.UserID)
+	}
+	if len(newAttendances) == 0 {
+		return result, nil
+	}
+
+	if err := tx.CreateInBatches(newAttendances, 100).Error; err != nil {
+		return result, err
+	}
+	if err := extendStreaks(tx, newAttendances); err != nil {
+		return result, err
+	}
+	transactions := make([]models.PointTransaction, 0, len(newAttendances))
+	note := fmt.Sprintf("Attended %q", event.Name)
+	for _, attendance := range newAttendances {
+		transactions = append(transactions, models.PointTransaction{
+			UserID:       attendance.UserID,
+			Delta:        event.PointsAllocation,
+			Reason:       string(models.PointReasonAttendance),
+			EventID:      &event.ID,
+			AttendanceID: &attendance.ID,
+			ActorID:      actorPointer(actorID),
+			Note:         note,
+		})
+	}
+	changes, err := PostPoints(tx, transactions)
+	if err != nil {
+		return result, err
+	}
+	// Award criteria look at attendance itself, so events without points still count
+	if event.PointsAllocation == 0 {
+		if changes, err = EvaluateAwards(tx, result.NewAttendees); err != nil {
+			return result, err
+		}
+	}
+	result.AwardsGranted = changes.Granted
+	result.AwardsEarned = changes.EarnedByUser()
+
+	return result, nil
+}
+
+// RemoveAttendances deletes the attendance of users at an event, takes back the points they were credited, the
+// survey bonus they got for it and the event's awards, and recomputes their streaks. It returns the users whose
+// attendance was actually removed and the awards they lost. Users who didn't attend are left alone.
+func RemoveAttendances(tx *gorm.DB, event models.Event, userIDs []uint, actorID uint) ([]uint, AwardChanges, error) {
+	if len(userIDs) == 0 {
+		return nil, AwardChanges{}, nil
+	}
+
+	var removed []models.Attendance
+	if err := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "user_id"}}}).
+		Where("event_id = ? AND user_id IN ?", event.ID, userIDs).
+		Delete(&removed).Error; err != nil {
+		return nil, AwardChanges{}, err
+	}
+
+	// Attendees were credited once however many times they were scanned
+	var removedUserIDs []uint
+	transactions := make([]models.PointTransaction, 0, len(removed))
+	note := fmt.Sprintf("Attendance at %q was removed", event.Name)
+	seen := make(map[uint]bool, len(removed))
+	for _, attendance := range removed {
+		if seen[attendance.UserID] {
+			continue
+		}
+		seen[attendance.UserID] = true
+		removedUserIDs = append(removedUserIDs, attendance.UserID)
+		transactions = append(transactions, models.PointTransaction{
+			UserID:       attendance.UserID,
+			Delta:        -event.PointsAllocation,
+			Reason:       string(models.PointReasonAttendanceRemoved),
+			EventID:      &event.ID,
+			AttendanceID: &attendance.ID,
+			ActorID:      actorPointer(actorID),
+			Note:         note,
+		})
+	}
+	if len(removedUserIDs) == 0 {
+		return nil, AwardChanges{}, nil
+	}
+	if err := RecomputeStreaks(tx, removedUserIDs); err != nil {
+		return nil, AwardChanges{}, err
+	}
+
+	// The survey bonus was earned by attending, so it goes too and the response leaves the results
+	reversals, err := surveyBonusReversals(tx, event, removedUserIDs, surveyBonusReasons, models.PointReasonSurveyBonusRemoved, actorID, note)
+	if err != nil {
+		return nil, AwardChanges{}, err
+	}
+	transactions = append(transactions, reversals...)
+	if err := tx.Exec(`DELETE FROM survey_answers WHERE response_id IN (
+		SELECT r.id FROM survey_responses r JOIN surveys s ON s.id = r.survey_id WHERE s.event_id = ? AND r.user_id IN ?)`,
+		event.ID, removedUserIDs).Error; err != nil {
+		return nil, AwardChanges{}, err
+	}
+	if err := tx.Exec("DELETE FROM survey_responses WHERE user_id IN ? AND survey_id IN (SELECT id FROM surveys WHERE event_id = ?)",
+		removedUserIDs, event.ID).Error; err != nil {
+		return nil, AwardChanges{}, err
+	}
+
+	changes, err := PostPoints(tx, transactions)
+	if err == nil && event.PointsAllocation == 0 {
+		// Nothing may have been posted for some of them, but losing the event can still cost them awards
+		var more AwardChanges
+		more, err = EvaluateAwards(tx, removedUserIDs)
+		changes.add(more)
+	}
+	return removedUserIDs, changes, err
+}
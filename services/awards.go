@@ -0,0 +1,468 @@
+package services
+
+import (
+	"os"
+	"slices"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/rules"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Users are re-evaluated in batches of this size when an award changes
+const awardEvaluationBatch = 500
+
+// automaticBadgeSources are the badges award evaluation manages, badges granted any other way are left alone
+var automaticBadgeSources = []models.BadgeSource{models.BadgeSourceThreshold, models.BadgeSourceCriteria}
+
+// AwardChanges counts the badges an award evaluation granted and revoked
+type AwardChanges struct {
+	Granted int64         `json:"granted"`
+	Revoked int64         `json:"revoked"`
+	Earned  []EarnedAward `json:"-"` // The badges behind Granted
+}
+
+// EarnedAward is a badge granted by an award evaluation
+type EarnedAward struct {
+	UserID  uint
+	AwardID uint
+}
+
+func (c *AwardChanges) add(other AwardChanges) {
+	c.Granted += other.Granted
+	c.Revoked += other.Revoked
+	c.Earned = append(c.Earned, other.Earned...)
+}
+
+// EarnedByUser groups the earned awards by user
+func (c AwardChanges) EarnedByUser() map[uint][]uint {
+	earned := make(map[uint][]uint)
+	for _, badge := range c.Earned {
+		earned[badge.UserID] = append(earned[badge.UserID], badge.AwardID)
+	}
+	return earned
+}
+
+// AwardsPermanent reports whether badges are kept once earned, even when the user no longer qualifies,
+// configured with AWARDS_PERMANENT ("true" or "false", default false)
+func AwardsPermanent() bool {
+	return os.Getenv("AWARDS_PERMANENT") == "true"
+}
+
+// EvaluateAwards brings the badges of the given users in line with what they qualify for: awards attached to
+// events they attended are granted, awards with criteria follow the rules engine and the others their points
+// threshold. Qualifying awards are granted and, unless awards are permanent, the ones users no longer qualify
+// for are revoked. PostPoints runs it on every points change.
+func EvaluateAwards(tx *gorm.DB, userIDs []uint) (AwardChanges, error) {
+	return evaluateAwards(tx, userIDs, 0)
+}
+
+// ReevaluateAward checks a single award for every user, after its threshold or criteria changed
+func ReevaluateAward(tx *gorm.DB, awardID uint) (AwardChanges, error) {
+	var changes AwardChanges
+	var userIDs []uint
+	if err := tx.Model(&models.User{}).Order("id").Pluck("id", &userIDs).Error; err != nil {
+		return changes, err
+	}
+	for start := 0; start < len(userIDs); start += awardEvaluationBatch {
+		batch, err := evaluateAwards(tx, userIDs[start:min(start+awardEvaluationBatch, len(userIDs))], awardID)
+		if err != nil {
+			return changes, err
+		}
+		changes.add(batch)
+	}
+	return changes, nil
+}
+
+// EvaluateEventAttendees re-evaluates the awards of everyone who attended an event, for changes to the event
+// that awards look at without moving any points (like its category or attached awards)
+func EvaluateEventAttendees(tx *gorm.DB, event models.Event) (AwardChanges, error) {
+	var userIDs []uint
+	if err := tx.Model(&models.Attendance{}).Where("event_id = ?", event.ID).
+		Distinct().Pluck("user_id", &userIDs).Error; err != nil {
+		return AwardChanges{}, err
+	}
+	return EvaluateAwards(tx, userIDs)
+}
+
+// QualifyingUsers returns the users who meet the criteria right now, without granting anything
+func QualifyingUsers(db *gorm.DB, criteria rules.Criteria) ([]uint, error) {
+	var userIDs []uint
+	if err := db.Model(&models.User{}).Order("id").Pluck("id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+
+	qualifying := []uint{}
+	for start := 0; start < len(userIDs); start += awardEvaluationBatch {
+		batch := userIDs[start:min(start+awardEvaluationBatch, len(userIDs))]
+		facts, err := loadFacts(db, batch, criteria.NeedsAttendances(), criteria.NeedsPoints())
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range batch {
+			if criteria.Evaluate(facts[userID]) {
+				qualifying = append(qualifying, userID)
+			}
+		}
+	}
+	return qualifying, nil
+}
+
+// evaluateAwards evaluates every award, or only awardID when it isn't 0, for the given users
+func evaluateAwards(tx *gorm.DB, userIDs []uint, awardID uint) (AwardChanges, error) {
+	var changes AwardChanges
+	if len(userIDs) == 0 {
+		return changes, nil
+	}
+
+	// Event badges go first, a badge taken away with its event may still be earned another way below
+	event, revokedBadges, err := evaluateEventAwards(tx, userIDs, awardID)
+	if err != nil {
+		return changes, err
+	}
+	changes.add(event)
+
+	threshold, err := evaluateThresholdAwards(tx, userIDs, awardID)
+	if err != nil {
+		return changes, err
+	}
+	changes.add(threshold)
+
+	criteria, err := evaluateCriteriaAwards(tx, userIDs, awardID)
+	if err != nil {
+		return changes, err
+	}
+	changes.add(criteria)
+
+	// A badge revoked and granted again in the same evaluation never changed hands
+	if len(revokedBadges) == 0 {
+		return changes, nil
+	}
+	revoked := make(map[EarnedAward]bool, len(revokedBadges))
+	for _, badge := range revokedBadges {
+		revoked[badge] = true
+	}
+	earned := changes.Earned[:0]
+	for _, badge := range changes.Earned {
+		if revoked[badge] {
+			changes.Granted--
+			changes.Revoked--
+			continue
+		}
+		earned = append(earned, badge)
+	}
+	changes.Earned = earned
+
+	return changes, nil
+}
+
+// evaluateEventAwards grants the awards attached to events the users attended and revokes the ones they only
+// held through an attendance that was removed or an event that was deleted, returning the revoked badges.
+// Attendance outweighs the other ways of earning a badge, so automatic badges of attended events become event
+// badges. Like every other badge, event badges are kept when awards are permanent.
+func evaluateEventAwards(tx *gorm.DB, userIDs []uint, awardID uint) (AwardChanges, []EarnedAward, error) {
+	var changes AwardChanges
+	const attendedEvent = `
+		SELECT 1 FROM attendances att
+		JOIN events e ON e.id = att.event_id AND e.deleted_at IS NULL
+		JOIN event_awards ea ON ea.event_id = e.id
+		WHERE att.user_id = ub.user_id AND ea.award_id = ub.award_id`
+
+	now := time.Now()
+	if err := tx.Exec(`
+		UPDATE user_badges ub SET source = ?, updated_at = ?
+		WHERE ub.user_id IN (?)
+		  AND (? = 0 OR ub.award_id = ?)
+		  AND ub.source IN (?)
+		  AND EXISTS (`+attendedEvent+`)
+	`, models.BadgeSourceEvent, now, userIDs, awardID, awardID, automaticBadgeSources).Error; err != nil {
+		return changes, nil, err
+	}
+
+	if err := tx.Raw(`
+		INSERT INTO user_badges (user_id, award_id, source, created_at, updated_at)
+		SELECT DISTINCT att.user_id, ea.award_id, ?, ?, ?
+		FROM attendances att
+		JOIN events e ON e.id = att.event_id AND e.deleted_at IS NULL
+		JOIN event_awards ea ON ea.event_id = e.id
+		WHERE att.user_id IN (?)
+		  AND (? = 0 OR ea.award_id = ?)
+		ON CONFLICT (user_id, award_id) DO NOTHING
+		RETURNING user_id, award_id
+	`, models.BadgeSourceEvent, now, now, userIDs, awardID, awardID).Scan(&changes.Earned).Error; err != nil {
+		return changes, nil, err
+	}
+	changes.Granted = int64(len(changes.Earned))
+
+	if AwardsPermanent() {
+		return changes, nil, nil
+	}
+	var revoked []EarnedAward
+	if err := tx.Raw(`
+		DELETE FROM user_badges ub
+		WHERE ub.user_id IN (?)
+		  AND (? = 0 OR ub.award_id = ?)
+		  AND ub.source = ?
+		  AND NOT EXISTS (`+attendedEvent+`)
+		RETURNING ub.user_id, ub.award_id
+	`, userIDs, awardID, awardID, models.BadgeSourceEvent).Scan(&revoked).Error; err != nil {
+		return changes, nil, err
+	}
+	changes.Revoked = int64(len(revoked))
+
+	return changes, revoked, nil
+}
+
+// earnedPointsSQL is what a user (u) earned, spending points on rewards doesn't cost badges. It takes the
+// spending reasons as its bind variable.
+const earnedPointsSQL = `u.current_points - COALESCE((SELECT SUM(pt.delta) FROM point_transactions pt
+		WHERE pt.user_id = u.id AND pt.reason IN (?)), 0)`
+
+// linkedToEvent holds for awards (a) attached to an event, those are only earned by attending it
+const linkedToEvent = `EXISTS (SELECT 1 FROM event_awards ea WHERE ea.award_id = a.id)`
+
+// evaluateThresholdAwards grants and revokes the awards without criteria by comparing their points to what
+// users earned. Awards attached to events are only granted by attendance, but the threshold badges users
+// already hold for them are kept as long as their points cover the award.
+func evaluateThresholdAwards(tx *gorm.DB, userIDs []uint, awardID uint) (AwardChanges, error) {
+	var changes AwardChanges
+
+	// Insert qualifying awards, avoid adding duplicates
+	now := time.Now()
+	if err := tx.Raw(`
+		INSERT INTO user_badges (user_id, award_id, source, created_at, updated_at)
+		SELECT u.id, a.id, ?, ?, ?
+		FROM users u
+		CROSS JOIN awards a
+		LEFT JOIN user_badges ub ON ub.user_id = u.id AND ub.award_id = a.id
+		WHERE u.id IN (?)
+		  AND (? = 0 OR a.id = ?)
+		  AND a.criteria IS NULL
+		  AND NOT `+linkedToEvent+`
+		  AND a.points <= `+earnedPointsSQL+`
+		  AND ub.user_id IS NULL
+		RETURNING user_id, award_id
+	`, models.BadgeSourceThreshold, now, now, userIDs, awardID, awardID, spendingReasons).Scan(&changes.Earned).Error; err != nil {
+		return changes, err
+	}
+	changes.Granted = int64(len(changes.Earned))
+
+	if AwardsPermanent() {
+		return changes, nil
+	}
+	revoked := tx.Exec(`
+		DELETE FROM user_badges ub
+		USING users u, awards a
+		WHERE ub.user_id = u.id AND ub.award_id = a.id
+		  AND u.id IN (?)
+		  AND (? = 0 OR a.id = ?)
+		  AND ub.source IN (?)
+		  AND a.criteria IS NULL
+		  AND a.points > `+earnedPointsSQL+`
+	`, userIDs, awardID, awardID, automaticBadgeSources, spendingReasons)
+	if revoked.Error != nil {
+		return changes, revoked.Error
+	}
+	changes.Revoked = revoked.RowsAffected
+
+	return changes, nil
+}
+
+// evaluateCriteriaAwards grants and revokes the awards with criteria by running the rules engine over each user
+func evaluateCriteriaAwards(tx *gorm.DB, userIDs []uint, awardID uint) (AwardChanges, error) {
+	var changes AwardChanges
+
+	query := tx.Where("criteria IS NOT NULL")
+	if awardID != 0 {
+		query = query.Where("id = ?", awardID)
+	}
+	var awardList []models.Award
+	if err := query.Find(&awardList).Error; err != nil || len(awardList) == 0 {
+		return changes, err
+	}
+
+	needAttendances, needPoints := false, false
+	awardIDs := make([]uint, 0, len(awardList))
+	for _, award := range awardList {
+		needAttendances = needAttendances || award.Criteria.NeedsAttendances()
+		needPoints = needPoints || award.Criteria.NeedsPoints()
+		awardIDs = append(awardIDs, award.ID)
+	}
+	facts, err := loadFacts(tx, userIDs, needAttendances, needPoints)
+	if err != nil {
+		return changes, err
+	}
+
+	// Current badges, only the automatic ones may be revoked
+	var badges []models.UserBadge
+	if err := tx.Where("user_id IN ? AND award_id IN ?", userIDs, awardIDs).Find(&badges).Error; err != nil {
+		return changes, err
+	}
+	type badgeKey struct{ userID, awardID uint }
+	held := make(map[badgeKey]models.UserBadge, len(badges))
+	for _, badge := range badges {
+		held[badgeKey{badge.UserID, badge.AwardID}] = badge
+	}
+
+	// Truncated to what Postgres stores so our grants can be told apart by their creation time
+	now := time.Now().Truncate(time.Microsecond)
+	var grants []models.UserBadge
+	revokes := make(map[uint][]uint) // Award ID to users
+	for _, award := range awardList {
+		for _, userID := range userIDs {
+			badge, holds := held[badgeKey{userID, award.ID}]
+			qualifies := award.Criteria.Evaluate(facts[userID])
+			switch {
+			case qualifies && !holds:
+				grants = append(grants, models.UserBadge{
+					UserID:    userID,
+					AwardID:   award.ID,
+					Source:    string(models.BadgeSourceCriteria),
+					CreatedAt: now,
+					UpdatedAt: now,
+				})
+			case !qualifies && holds && isAutomaticBadge(badge):
+				revokes[award.ID] = append(revokes[award.ID], userID)
+			}
+		}
+	}
+
+	if len(grants) > 0 {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(grants, 100)
+		if result.Error != nil {
+			return changes, result.Error
+		}
+		changes.Granted = result.RowsAffected
+		if err := earnedBadges(tx, grants, now, &changes); err != nil {
+			return changes, err
+		}
+	}
+	if AwardsPermanent() {
+		return changes, nil
+	}
+	for revokedAwardID, revokedUserIDs := range revokes {
+		result := tx.Where("award_id = ? AND user_id IN ? AND source IN ?", revokedAwardID, revokedUserIDs, automaticBadgeSources).
+			Delete(&models.UserBadge{})
+		if result.Error != nil {
+			return changes, result.Error
+		}
+		changes.Revoked += result.RowsAffected
+	}
+
+	return changes, nil
+}
+
+// earnedBadges fills in the badges behind changes.Granted after inserting grants, a concurrent evaluation may
+// have granted some of them first
+func earnedBadges(tx *gorm.DB, grants []models.UserBadge, createdAt time.Time, changes *AwardChanges) error {
+	if int(changes.Granted) == len(grants) {
+		for _, grant := range grants {
+			changes.Earned = append(changes.Earned, EarnedAward{UserID: grant.UserID, AwardID: grant.AwardID})
+		}
+		return nil
+	}
+
+	userIDs := make([]uint, 0, len(grants))
+	awardIDs := make([]uint, 0, len(grants))
+	for _, grant := range grants {
+		userIDs = append(userIDs, grant.UserID)
+		awardIDs = append(awardIDs, grant.AwardID)
+	}
+	var inserted []EarnedAward
+	if err := tx.Model(&models.UserBadge{}).Select("user_id", "award_id").
+		Where("user_id IN ? AND award_id IN ? AND source = ? AND created_at = ?", userIDs, awardIDs, models.BadgeSourceCriteria, createdAt).
+		Scan(&inserted).Error; err != nil {
+		return err
+	}
+	changes.Earned = append(changes.Earned, inserted...)
+	return nil
+}
+
+// loadFacts gathers what the rules engine looks at for each user, every user gets facts even without any.
+// Streaks come from the stored streaks, attendance and the ledger are only loaded when asked for.
+func loadFacts(db *gorm.DB, userIDs []uint, withAttendances, withPoints bool) (map[uint]*rules.Facts, error) {
+	facts := make(map[uint]*rules.Facts, len(userIDs))
+	for _, userID := range userIDs {
+		facts[userID] = &rules.Facts{}
+	}
+
+	var streaks []models.UserStreak
+	if err := db.Select("user_id", "longest_weeks").Where("user_id IN ?", userIDs).Find(&streaks).Error; err != nil {
+		return nil, err
+	}
+	for _, streak := range streaks {
+		facts[streak.UserID].LongestStreak = streak.LongestWeeks
+	}
+
+	if withAttendances {
+		var attendances []struct {
+			UserID      uint
+			EventID     uint
+			CategoryID  *uint
+			ScannedTime time.Time
+		}
+		if err := db.Table("attendances").
+			Select("attendances.user_id, attendances.event_id, events.category_id, attendances.scanned_time").
+			Joins("JOIN events ON events.id = attendances.event_id AND events.deleted_at IS NULL").
+			Where("attendances.user_id IN ?", userIDs).
+			Scan(&attendances).Error; err != nil {
+			return nil, err
+		}
+		for _, attendance := range attendances {
+			facts[attendance.UserID].Attendances = append(facts[attendance.UserID].Attendances, rules.Attendance{
+				EventID:    attendance.EventID,
+				CategoryID: attendance.CategoryID,
+				At:         attendance.ScannedTime,
+			})
+		}
+	}
+
+	if withPoints {
+		// Entries are dated like term balances, by the start of their event
+		var entries []ledgerFact
+		if err := db.Table("point_transactions").
+			Select("point_transactions.user_id, point_transactions.delta, point_transactions.reason, "+termAttributedAt+" AS at").
+			Joins("LEFT JOIN events ON events.id = point_transactions.event_id").
+			Where("point_transactions.user_id IN ?", userIDs).
+			Scan(&entries).Error; err != nil {
+			return nil, err
+		}
+		addPointFacts(facts, entries)
+	}
+
+	return facts, nil
+}
+
+// ledgerFact is a ledger entry dated by when it counts towards a term, see termAttributedAt
+type ledgerFact struct {
+	UserID uint
+	Delta  int
+	Reason models.PointReason
+	At     time.Time
+}
+
+// Helper function to add ledger entries to the users' facts, leaving out the ones that don't count towards any
+// term (spending, opening balances and reconciliation corrections)
+func addPointFacts(facts map[uint]*rules.Facts, entries []ledgerFact) {
+	for _, entry := range entries {
+		if slices.Contains(termlessReasons, entry.Reason) {
+			continue
+		}
+		facts[entry.UserID].Points = append(facts[entry.UserID].Points, rules.PointEntry{
+			Delta: entry.Delta,
+			At:    entry.At,
+		})
+	}
+}
+
+// Helper function to check whether award evaluation manages a badge
+func isAutomaticBadge(badge models.UserBadge) bool {
+	for _, source := range automaticBadgeSources {
+		if badge.Source == string(source) {
+			return true
+		}
+	}
+	return false
+}
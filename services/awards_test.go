@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/rules"
+)
+
+func TestAddPointFacts(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	inTerm := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	facts := map[uint]*rules.Facts{1: {}, 2: {}}
+	addPointFacts(facts, []ledgerFact{
+		// The backfill restates everything user 1 earned before, on the day it ran
+		{UserID: 1, Delta: 500, Reason: models.PointReasonOpeningBalance, At: inTerm},
+		{UserID: 1, Delta: 40, Reason: models.PointReasonReconciliation, At: inTerm},
+		{UserID: 1, Delta: 10, Reason: models.PointReasonAttendance, At: inTerm},
+		{UserID: 1, Delta: -30, Reason: models.PointReasonRedemption, At: inTerm},
+		{UserID: 2, Delta: 15, Reason: models.PointReasonAttendance, At: inTerm},
+		{UserID: 2, Delta: 5, Reason: models.PointReasonSurveyCompleted, At: inTerm},
+	})
+
+	tests := []struct {
+		name   string
+		userID uint
+		points int
+		want   bool
+	}{
+		{"opening balance left out", 1, 11, false},
+		{"attendance counted", 1, 10, true},
+		{"survey bonus counted", 2, 20, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criteria := rules.Criteria{Type: rules.RulePointsInTerm, Points: tt.points, Start: &start, End: &end}
+			if got := criteria.Evaluate(facts[tt.userID]); got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"os"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"gorm.io/gorm"
+)
+
+// Deleted events are kept this long unless EVENT_PURGE_AFTER says otherwise
+const defaultEventPurgeAfter = 30 * 24 * time.Hour
+
+// EventPurgeAfter is how long a deleted event can be restored before it is purged for good,
+// configured with EVENT_PURGE_AFTER as a Go duration (e.g. "720h")
+func EventPurgeAfter() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("EVENT_PURGE_AFTER")); err == nil && value > 0 {
+		return value
+	}
+	return defaultEventPurgeAfter
+}
+
+// PurgeDeletedEvents hard-deletes the events deleted before the given time along with their
+// attendances, RSVPs, join links, surveys and associations, and returns how many events were purged.
+// Points were already taken back when the events were deleted, so balances are left alone.
+func PurgeDeletedEvents(db *gorm.DB, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var eventIDs []uint
+		if err := tx.Unscoped().Model(&models.Event{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+			Pluck("id", &eventIDs).Error; err != nil {
+			return err
+		}
+		if len(eventIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Where("event_id IN ?", eventIDs).Delete(&models.Attendance{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id IN ?", eventIDs).Delete(&models.RSVP{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id IN ?", eventIDs).Delete(&models.JoinLink{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM survey_answers WHERE response_id IN (
+			SELECT r.id FROM survey_responses r JOIN surveys s ON s.id = r.survey_id WHERE s.event_id IN ?)`, eventIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM survey_responses WHERE survey_id IN (SELECT id FROM surveys WHERE event_id IN ?)", eventIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM survey_questions WHERE survey_id IN (SELECT id FROM surveys WHERE event_id IN ?)", eventIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id IN ?", eventIDs).Delete(&models.Survey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM event_awards WHERE event_id IN ?", eventIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM event_tags WHERE event_id IN ?", eventIDs).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("id IN ?", eventIDs).Delete(&models.Event{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
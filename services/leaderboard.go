@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// Cached leaderboards are recomputed this often unless LEADERBOARD_REFRESH_INTERVAL says otherwise
+	defaultLeaderboardRefreshInterval = 5 * time.Minute
+	// Cached leaderboards nobody asked for in this long are dropped instead of recomputed
+	leaderboardIdleAfter = time.Hour
+	// At most this many leaderboards are cached, the least recently asked for is dropped to make room
+	maxCachedLeaderboards = 100
+)
+
+// LeaderboardFilter narrows a leaderboard down to a cohort, department, term, time range or category. Without
+// a term, time range or category users are ranked by the points they earned, otherwise by the ledger entries
+// that match.
+type LeaderboardFilter struct {
+	GradYear   *int
+	Department string
+	TermID     *uint      // Only points attributed to this term
+	From       *time.Time // Ledger entries from this time on
+	To         *time.Time // Ledger entries before this time
+	CategoryID *uint      // Only points of events in this category
+}
+
+// UsesLedger reports whether the leaderboard sums ledger entries instead of using balances
+func (f LeaderboardFilter) UsesLedger() bool {
+	return f.TermID != nil || f.From != nil || f.To != nil || f.CategoryID != nil
+}
+
+// key identifies the filter in the leaderboard cache
+func (f LeaderboardFilter) key() string {
+	key := fmt.Sprintf("department=%s", f.Department)
+	if f.GradYear != nil {
+		key += fmt.Sprintf(";grad_year=%d", *f.GradYear)
+	}
+	if f.TermID != nil {
+		key += fmt.Sprintf(";term=%d", *f.TermID)
+	}
+	if f.From != nil {
+		key += ";from=" + f.From.UTC().Format(time.RFC3339)
+	}
+	if f.To != nil {
+		key += ";to=" + f.To.UTC().Format(time.RFC3339)
+	}
+	if f.CategoryID != nil {
+		key += fmt.Sprintf(";category=%d", *f.CategoryID)
+	}
+	return key
+}
+
+// LeaderboardEntry is a ranked user, users with the same points share a rank (dense ranking)
+type LeaderboardEntry struct {
+	Rank              int    `json:"rank"`
+	UserID            uint   `json:"user_id"`
+	Name              string `json:"name"`
+	PhotoThumbnailURL string `json:"photo_thumbnail_url"`
+	GradYear          int    `json:"grad_year"`
+	Department        string `json:"department"`
+	Points            int    `json:"points"`
+}
+
+// Leaderboard is a ranking of every user matching a filter, as computed at GeneratedAt
+type Leaderboard struct {
+	Entries     []LeaderboardEntry
+	GeneratedAt time.Time
+	positions   map[uint]int // User ID to index in Entries
+}
+
+// Top returns the first n entries
+func (l *Leaderboard) Top(n int) []LeaderboardEntry {
+	return l.Entries[:min(n, len(l.Entries))]
+}
+
+// Position returns the entry of a user, users who opted out or don't match the filter have none
+func (l *Leaderboard) Position(userID uint) (LeaderboardEntry, bool) {
+	index, ok := l.positions[userID]
+	if !ok {
+		return LeaderboardEntry{}, false
+	}
+	return l.Entries[index], true
+}
+
+// cachedLeaderboard is a leaderboard in the cache along with when it was last asked for
+type cachedLeaderboard struct {
+	filter   LeaderboardFilter
+	board    *Leaderboard
+	lastRead time.Time
+}
+
+var (
+	leaderboardCache   = make(map[string]*cachedLeaderboard) // Key: filter key
+	muLeaderboardCache sync.Mutex
+)
+
+// LeaderboardRefreshInterval is how often cached leaderboards are recomputed, configured with
+// LEADERBOARD_REFRESH_INTERVAL as a Go duration (e.g. "5m")
+func LeaderboardRefreshInterval() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("LEADERBOARD_REFRESH_INTERVAL")); err == nil && value > 0 {
+		return value
+	}
+	return defaultLeaderboardRefreshInterval
+}
+
+// GetLeaderboard returns the leaderboard of a filter from the cache, computing it on first use. Cached
+// leaderboards lag behind balances by up to LeaderboardRefreshInterval. Leaderboards of a time range are
+// computed on every call, any range can be asked for so caching them would let callers fill the cache.
+func GetLeaderboard(db *gorm.DB, filter LeaderboardFilter) (*Leaderboard, error) {
+	if filter.From != nil || filter.To != nil {
+		return computeLeaderboard(db, filter)
+	}
+
+	key := filter.key()
+	muLeaderboardCache.Lock()
+	if cached, ok := leaderboardCache[key]; ok {
+		cached.lastRead = time.Now()
+		muLeaderboardCache.Unlock()
+		return cached.board, nil
+	}
+	muLeaderboardCache.Unlock()
+
+	board, err := computeLeaderboard(db, filter)
+	if err != nil {
+		return nil, err
+	}
+	muLeaderboardCache.Lock()
+	if _, ok := leaderboardCache[key]; !ok && len(leaderboardCache) >= maxCachedLeaderboards {
+		evictLeastRecentLeaderboard()
+	}
+	leaderboardCache[key] = &cachedLeaderboard{filter: filter, board: board, lastRead: time.Now()}
+	muLeaderboardCache.Unlock()
+	return board, nil
+}
+
+// evictLeastRecentLeaderboard drops the cached leaderboard asked for the longest ago, the cache must be locked
+func evictLeastRecentLeaderboard() {
+	oldestKey := ""
+	var oldest time.Time
+	for key, cached := range leaderboardCache {
+		if oldestKey == "" || cached.lastRead.Before(oldest) {
+			oldestKey, oldest = key, cached.lastRead
+		}
+	}
+	delete(leaderboardCache, oldestKey)
+}
+
+// RefreshLeaderboards recomputes the cached leaderboards, dropping the ones nobody asked for in a while,
+// and returns how many were recomputed
+func RefreshLeaderboards(db *gorm.DB) (int, error) {
+	muLeaderboardCache.Lock()
+	stale := make(map[string]LeaderboardFilter, len(leaderboardCache))
+	for key, cached := range leaderboardCache {
+		if time.Since(cached.lastRead) > leaderboardIdleAfter {
+			delete(leaderboardCache, key)
+			continue
+		}
+		stale[key] = cached.filter
+	}
+	muLeaderboardCache.Unlock()
+
+	refreshed := 0
+	for key, filter := range stale {
+		board, err := computeLeaderboard(db, filter)
+		if err != nil {
+			return refreshed, err
+		}
+		muLeaderboardCache.Lock()
+		if cached, ok := leaderboardCache[key]; ok {
+			cached.board = board
+		}
+		muLeaderboardCache.Unlock()
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// InvalidateLeaderboards empties the leaderboard cache, for changes that must show up right away (like a
+// user opting out)
+func InvalidateLeaderboards() {
+	muLeaderboardCache.Lock()
+	defer muLeaderboardCache.Unlock()
+	leaderboardCache = make(map[string]*cachedLeaderboard)
+}
+
+// computeLeaderboard ranks every user matching the filter, leaving out banned users and those who opted out
+func computeLeaderboard(db *gorm.DB, filter LeaderboardFilter) (*Leaderboard, error) {
+	// Spending points on rewards doesn't cost anyone their rank
+	points := "users.current_points - COALESCE(spent.delta, 0)"
+	query := db.Model(&models.User{})
+	if filter.UsesLedger() {
+		ledger := db.Model(&models.PointTransaction{}).
+			Select("point_transactions.user_id, SUM(point_transactions.delta) AS points").
+			Where("point_transactions.reason NOT IN ?", spendingReasons).
+			Group("point_transactions.user_id")
+		if filter.From != nil {
+			ledger = ledger.Where("point_transactions.created_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			ledger = ledger.Where("point_transactions.created_at < ?", *filter.To)
+		}
+		// Opening balances and repairs weren't earned on the day they were posted, like for terms
+		if filter.From != nil || filter.To != nil {
+			ledger = ledger.Where("point_transactions.reason NOT IN ?", termlessReasons)
+		}
+		if filter.CategoryID != nil {
+			// Deleted events are joined too, their removal entries cancel out what they gave
+			ledger = ledger.Joins("JOIN events ON events.id = point_transactions.event_id AND events.category_id = ?", *filter.CategoryID)
+		} else if filter.TermID != nil {
+			ledger = ledger.Joins("LEFT JOIN events ON events.id = point_transactions.event_id")
+		}
+		if filter.TermID != nil {
+			var term models.Term
+			if err := db.First(&term, *filter.TermID).Error; err != nil {
+				return nil, err
+			}
+			ledger = ledger.Where(termAttributedAt+" >= ? AND "+termAttributedAt+" < ?", term.StartsAt, term.EndsAt).
+				Where("point_transactions.reason NOT IN ?", termlessReasons)
+		}
+		points = "COALESCE(ledger.points, 0)"
+		query = query.Joins("LEFT JOIN (?) AS ledger ON ledger.user_id = users.id", ledger)
+	} else {
+		query = query.Joins("LEFT JOIN (?) AS spent ON spent.user_id = users.id", spentPoints(db))
+	}
+
+	query = query.
+		Select(`users.id AS user_id, users.name, users.photo_thumbnail_url, users.grad_year, users.department,
+			`+points+` AS points, DENSE_RANK() OVER (ORDER BY `+points+` DESC) AS rank`).
+		Where("users.leaderboard_opt_out = ? AND users.status <> ?", false, models.StatusBanned)
+	if filter.GradYear != nil {
+		query = query.Where("users.grad_year = ?", *filter.GradYear)
+	}
+	if filter.Department != "" {
+		query = query.Where("LOWER(users.department) = LOWER(?)", filter.Department)
+	}
+
+	board := &Leaderboard{GeneratedAt: time.Now()}
+	if err := query.Order("rank, users.name, users.id").Scan(&board.Entries).Error; err != nil {
+		return nil, err
+	}
+	board.positions = make(map[uint]int, len(board.Entries))
+	for i, entry := range board.Entries {
+		board.positions[entry.UserID] = i
+	}
+	return board, nil
+}
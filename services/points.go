@@ -0,0 +1,294 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// spendingReasons are the ledger reasons of points spent on rewards and refunded. They move the balance but not
+// what a user earned, which is what awards, rankings and term balances go by.
+var spendingReasons = []models.PointReason{models.PointReasonRedemption, models.PointReasonRedemptionRefund}
+
+// surveyBonusReasons are the ledger reasons whose sum is the survey bonus a user currently holds for an event.
+// Bonuses removed with the attendance (survey_bonus_removed) aren't brought back when a deleted event is restored.
+var surveyBonusReasons = []models.PointReason{models.PointReasonSurveyCompleted, models.PointReasonSurveyBonusRevoked,
+	models.PointReasonSurveyBonusRestored, models.PointReasonSurveyBonusRemoved}
+
+// openingBalancesMigration marks BackfillOpeningBalances as applied
+const openingBalancesMigration = "opening_balances"
+
+// EventPointsAdjustment summarizes a retroactive change of an event's points
+type EventPointsAdjustment struct {
+	Delta         int   `json:"delta"`          // Points added to (or removed from) every attendee
+	UsersAdjusted int   `json:"users_adjusted"` // Attendees whose balance changed
+	AwardsGranted int64 `json:"awards_granted"` // Awards attendees became eligible for
+	AwardsRevoked int64 `json:"awards_revoked"` // Awards attendees no longer qualify for
+}
+
+// RecalculateEventPoints brings the balance of everyone who attended an event in line with its current
+// points allocation, previousPoints being what they were credited with. It must run inside the transaction
+// that changed the allocation so balances and the event never disagree.
+func RecalculateEventPoints(tx *gorm.DB, event models.Event, previousPoints int, actorID uint) (EventPointsAdjustment, error) {
+	note := fmt.Sprintf("Points of %q changed from %d to %d", event.Name, previousPoints, event.PointsAllocation)
+	return adjustAttendeePoints(tx, event, event.PointsAllocation-previousPoints, models.PointReasonEventPointsChanged, actorID, note)
+}
+
+// RevokeEventPoints takes the points of an event back from everyone who attended it, along with the survey
+// bonuses they got for it
+func RevokeEventPoints(tx *gorm.DB, event models.Event, actorID uint) (EventPointsAdjustment, error) {
+	note := fmt.Sprintf("%q was deleted", event.Name)
+	adjustment, err := adjustAttendeePoints(tx, event, -event.PointsAllocation, models.PointReasonEventDeleted, actorID, note)
+	if err != nil {
+		return adjustment, err
+	}
+	// Survey bonuses still credited (net of earlier reversals) are taken back
+	changes, err := reverseSurveyBonuses(tx, event, nil, surveyBonusReasons, models.PointReasonSurveyBonusRevoked, actorID, note)
+	adjustment.AwardsGranted += changes.Granted
+	adjustment.AwardsRevoked += changes.Revoked
+	return adjustment, err
+}
+
+// RestoreEventPoints credits the points of an event again to everyone who attended it, along with the survey
+// bonuses taken back when it was deleted
+func RestoreEventPoints(tx *gorm.DB, event models.Event, actorID uint) (EventPointsAdjustment, error) {
+	note := fmt.Sprintf("%q was restored", event.Name)
+	adjustment, err := adjustAttendeePoints(tx, event, event.PointsAllocation, models.PointReasonEventRestored, actorID, note)
+	if err != nil {
+		return adjustment, err
+	}
+	// Reversals not yet restored are undone
+	changes, err := reverseSurveyBonuses(tx, event, nil, []models.PointReason{models.PointReasonSurveyBonusRevoked,
+		models.PointReasonSurveyBonusRestored}, models.PointReasonSurveyBonusRestored, actorID, note)
+	adjustment.AwardsGranted += changes.Granted
+	adjustment.AwardsRevoked += changes.Revoked
+	return adjustment, err
+}
+
+// reverseSurveyBonuses posts, for every user (or only userIDs when given), the opposite of the sum of their
+// ledger entries of an event with the given reasons. The reversals have reasons of their own (not event_deleted
+// or event_restored) because reconciliation counts them like the survey bonuses they reverse.
+func reverseSurveyBonuses(tx *gorm.DB, event models.Event, userIDs []uint, reasons []models.PointReason, reason models.PointReason, actorID uint, note string) (AwardChanges, error) {
+	transactions, err := surveyBonusReversals(tx, event, userIDs, reasons, reason, actorID, note)
+	if err != nil {
+		return AwardChanges{}, err
+	}
+	return PostPoints(tx, transactions)
+}
+
+// surveyBonusReversals builds the ledger entries reverseSurveyBonuses posts, without posting them
+func surveyBonusReversals(tx *gorm.DB, event models.Event, userIDs []uint, reasons []models.PointReason, reason models.PointReason, actorID uint, note string) ([]models.PointTransaction, error) {
+	var totals []struct {
+		UserID uint
+		Total  int
+	}
+	query := tx.Model(&models.PointTransaction{}).
+		Select("point_transactions.user_id, SUM(point_transactions.delta) AS total").
+		Joins("JOIN users ON users.id = point_transactions.user_id AND users.deleted_at IS NULL").
+		Where("point_transactions.event_id = ? AND point_transactions.reason IN ?", event.ID, reasons)
+	if userIDs != nil {
+		query = query.Where("point_transactions.user_id IN ?", userIDs)
+	}
+	if err := query.Group("point_transactions.user_id").
+		Having("SUM(point_transactions.delta) <> 0").
+		Order("point_transactions.user_id").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	transactions := make([]models.PointTransaction, 0, len(totals))
+	for _, total := range totals {
+		transactions = append(transactions, models.PointTransaction{
+			UserID:  total.UserID,
+			Delta:   -total.Total,
+			Reason:  string(reason),
+			EventID: &event.ID,
+			ActorID: actorPointer(actorID),
+			Note:    note,
+		})
+	}
+	return transactions, nil
+}
+
+// adjustAttendeePoints adds delta to the balance of every attendee of an event and records the change
+// in the ledger, which also re-evaluates their awards
+func adjustAttendeePoints(tx *gorm.DB, event models.Event, delta int, reason models.PointReason, actorID uint, note string) (EventPointsAdjustment, error) {
+	adjustment := EventPointsAdjustment{Delta: delta}
+	if delta == 0 {
+		// Nothing to post, but a deleted or restored event still changes what attendees qualify for
+		if reason == models.PointReasonEventDeleted || reason == models.PointReasonEventRestored {
+			changes, err := EvaluateEventAttendees(tx, event)
+			adjustment.AwardsGranted, adjustment.AwardsRevoked = changes.Granted, changes.Revoked
+			return adjustment, err
+		}
+		return adjustment, nil
+	}
+
+	var userIDs []uint
+	if err := tx.Model(&models.Attendance{}).
+		Joins("JOIN users ON users.id = attendances.user_id AND users.deleted_at IS NULL").
+		Where("attendances.event_id = ?", event.ID).
+		Distinct().
+		Pluck("attendances.user_id", &userIDs).Error; err != nil {
+		return adjustment, err
+	}
+	if len(userIDs) == 0 {
+		return adjustment, nil
+	}
+
+	transactions := make([]models.PointTransaction, 0, len(userIDs))
+	for _, userID := range userIDs {
+		transactions = append(transactions, models.PointTransaction{
+			UserID:  userID,
+			Delta:   delta,
+			Reason:  string(reason),
+			EventID: &event.ID,
+			ActorID: actorPointer(actorID),
+			Note:    note,
+		})
+	}
+	changes, err := PostPoints(tx, transactions)
+	if err != nil {
+		return adjustment, err
+	}
+	adjustment.UsersAdjusted = len(userIDs)
+	adjustment.AwardsGranted = changes.Granted
+	adjustment.AwardsRevoked = changes.Revoked
+
+	return adjustment, nil
+}
+
+// CreditUserPoints adds points to a single user's balance, records why in the ledger and grants the awards
+// the user became eligible for, returning the number of awards granted
+func CreditUserPoints(tx *gorm.DB, userID uint, points int, reason models.PointReason, eventID *uint, note string) (int64, error) {
+	if points == 0 {
+		return 0, nil
+	}
+	transaction := models.PointTransaction{
+		UserID:  userID,
+		Delta:   points,
+		Reason:  string(reason),
+		EventID: eventID,
+		Note:    note,
+	}
+	changes, err := PostPoints(tx, []models.PointTransaction{transaction})
+	return changes.Granted, err
+}
+
+// PostPoints appends transactions to the ledger and applies them to the balances cached in current_points.
+// Every balance change goes through here so the ledger always explains a user's balance and badges always follow
+// it. It must run inside a transaction, transactions with a zero delta are skipped and the others get their IDs set.
+func PostPoints(tx *gorm.DB, transactions []models.PointTransaction) (AwardChanges, error) {
+	entries := make([]models.PointTransaction, 0, len(transactions))
+	positions := make([]int, 0, len(transactions))
+	totals := make(map[uint]int)
+	for i, transaction := range transactions {
+		if transaction.Delta == 0 {
+			continue
+		}
+		entries = append(entries, transaction)
+		positions = append(positions, i)
+		totals[transaction.UserID] += transaction.Delta
+	}
+	if len(entries) == 0 {
+		return AwardChanges{}, nil
+	}
+	if err := tx.CreateInBatches(entries, 100).Error; err != nil {
+		return AwardChanges{}, err
+	}
+	for i, entry := range entries {
+		transactions[positions[i]].ID = entry.ID
+	}
+
+	// Users whose balance moves by the same amount are updated together, bulk changes usually share one delta.
+	// Updates go in a fixed order so concurrent postings lock users in the same order.
+	usersByDelta := make(map[int][]uint)
+	for userID, total := range totals {
+		if total != 0 {
+			usersByDelta[total] = append(usersByDelta[total], userID)
+		}
+	}
+	changedIDs := make([]uint, 0, len(totals))
+	deltas := make([]int, 0, len(usersByDelta))
+	for delta := range usersByDelta {
+		deltas = append(deltas, delta)
+	}
+	sort.Ints(deltas)
+	for _, delta := range deltas {
+		userIDs := usersByDelta[delta]
+		sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+		// Raw SQL because GORM refuses to write current_points, which keeps full-row saves of users from
+		// overwriting it with a stale value
+		if err := tx.Exec("UPDATE users SET current_points = current_points + ?, updated_at = ? WHERE id IN ?",
+			delta, time.Now(), userIDs).Error; err != nil {
+			return AwardChanges{}, err
+		}
+		changedIDs = append(changedIDs, userIDs...)
+	}
+
+	return EvaluateAwards(tx, changedIDs)
+}
+
+// LifetimePoints returns the points a user earned over all time, their balance plus what they spent
+func LifetimePoints(db *gorm.DB, userID uint) (int, error) {
+	var lifetime int
+	err := db.Model(&models.User{}).
+		Select("users.current_points - COALESCE(spent.delta, 0)").
+		Joins("LEFT JOIN (?) AS spent ON spent.user_id = users.id", spentPoints(db)).
+		Where("users.id = ?", userID).
+		Scan(&lifetime).Error
+	return lifetime, err
+}
+
+// spentPoints is a subquery of the net ledger change of spending per user (negative when points were spent),
+// users earned their current_points minus it
+func spentPoints(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.PointTransaction{}).
+		Select("point_transactions.user_id, SUM(point_transactions.delta) AS delta").
+		Where("point_transactions.reason IN ?", spendingReasons).
+		Group("point_transactions.user_id")
+}
+
+// BackfillOpeningBalances records an opening balance for every user whose balance predates the ledger, so the
+// ledger adds up to current_points for everyone, and returns how many were recorded. It is a one-time migration
+// marked as applied in data_migrations, later differences are drift for reconciliation to look into.
+func BackfillOpeningBalances(db *gorm.DB) (int64, error) {
+	var opened int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		marker := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.DataMigration{Name: openingBalancesMigration, AppliedAt: time.Now()})
+		if marker.Error != nil || marker.RowsAffected == 0 {
+			return marker.Error
+		}
+
+		// Deployments that ran the backfill before it was marked already have their opening balances
+		var existing int64
+		if err := tx.Model(&models.PointTransaction{}).Where("reason = ?", models.PointReasonOpeningBalance).
+			Count(&existing).Error; err != nil || existing > 0 {
+			return err
+		}
+
+		result := tx.Exec(`
+			INSERT INTO point_transactions (user_id, delta, reason, note, created_at)
+			SELECT u.id, u.current_points - COALESCE(l.total, 0), ?, ?, ?
+			FROM users u
+			LEFT JOIN (SELECT user_id, SUM(delta) AS total FROM point_transactions GROUP BY user_id) l ON l.user_id = u.id
+			WHERE u.current_points <> COALESCE(l.total, 0)
+		`, models.PointReasonOpeningBalance, "Balance before the points ledger was introduced", time.Now())
+		opened = result.RowsAffected
+		return result.Error
+	})
+	return opened, err
+}
+
+// Helper function to record system changes (actor 0) without an actor
+func actorPointer(actorID uint) *uint {
+	if actorID == 0 {
+		return nil
+	}
+	return &actorID
+}
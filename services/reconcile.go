@@ -0,0 +1,174 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Balances are reconciled this often unless POINTS_RECONCILE_INTERVAL says otherwise
+const defaultReconcileInterval = 24 * time.Hour
+
+// attendanceDerivedReasons are the ledger reasons that move points because of attendance, the expected balance
+// is recomputed from the attendances themselves instead. Reconciliation corrections are left out too, they are
+// what brings the ledger back to the expected balance.
+var attendanceDerivedReasons = []models.PointReason{
+	models.PointReasonOpeningBalance,
+	models.PointReasonAttendance,
+	models.PointReasonAttendanceRemoved,
+	models.PointReasonEventPointsChanged,
+	models.PointReasonEventDeleted,
+	models.PointReasonEventRestored,
+	models.PointReasonReconciliation,
+}
+
+// ReconcileInterval is how often the scheduled reconciliation runs, configured with
+// POINTS_RECONCILE_INTERVAL as a Go duration (e.g. "24h")
+func ReconcileInterval() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("POINTS_RECONCILE_INTERVAL")); err == nil && value > 0 {
+		return value
+	}
+	return defaultReconcileInterval
+}
+
+// balanceCheck is what a user has and should have
+type balanceCheck struct {
+	UserID         uint
+	CachedPoints   int
+	LedgerPoints   int
+	ExpectedPoints int
+}
+
+// ReconcilePoints compares every user's balance with the points they should have: the points of every
+// (non-deleted) event they attended plus their ledger entries unrelated to attendance, like survey bonuses.
+// Mismatches are recorded in a run. Unless dryRun is set, the cached balance is brought in line with the
+// ledger and a correcting transaction is posted so the ledger adds up to the expected points.
+// actorID is the admin who asked for it, 0 for the scheduled job.
+func ReconcilePoints(db *gorm.DB, dryRun bool, actorID uint) (models.ReconciliationRun, error) {
+	run := models.ReconciliationRun{
+		DryRun:        dryRun,
+		TriggeredByID: actorPointer(actorID),
+		StartedAt:     time.Now(),
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		checks, err := checkBalances(tx, nil)
+		if err != nil {
+			return err
+		}
+		run.UsersChecked = len(checks)
+
+		var mismatchedIDs []uint
+		for _, check := range checks {
+			if check.CachedPoints != check.LedgerPoints || check.LedgerPoints != check.ExpectedPoints {
+				mismatchedIDs = append(mismatchedIDs, check.UserID)
+			}
+		}
+
+		// Lock the users being corrected and check them again, points may have moved in the meantime
+		if !dryRun && len(mismatchedIDs) > 0 {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.User{}).
+				Where("id IN ?", mismatchedIDs).Order("id").Pluck("id", &[]uint{}).Error; err != nil {
+				return err
+			}
+			if checks, err = checkBalances(tx, mismatchedIDs); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(&run).Error; err != nil {
+			return err
+		}
+
+		for _, check := range checks {
+			if check.CachedPoints == check.LedgerPoints && check.LedgerPoints == check.ExpectedPoints {
+				continue
+			}
+			item := models.ReconciliationItem{
+				RunID:          run.ID,
+				UserID:         check.UserID,
+				CachedPoints:   check.CachedPoints,
+				LedgerPoints:   check.LedgerPoints,
+				ExpectedPoints: check.ExpectedPoints,
+				Correction:     check.ExpectedPoints - check.LedgerPoints,
+			}
+			run.Discrepancies++
+
+			if !dryRun {
+				if item.TransactionID, err = correctBalance(tx, check, run.ID, actorID); err != nil {
+					return err
+				}
+				item.Applied = true
+				run.Corrected++
+				run.NetCorrection += item.Correction
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+		}
+
+		finished := time.Now()
+		run.FinishedAt = &finished
+		return tx.Model(&run).Select("Discrepancies", "Corrected", "NetCorrection", "FinishedAt").Updates(&run).Error
+	})
+	return run, err
+}
+
+// checkBalances computes the cached, ledger and expected points of the given users, or of every user when nil
+func checkBalances(tx *gorm.DB, userIDs []uint) ([]balanceCheck, error) {
+	query := tx.Table("users u").
+		Select(`u.id AS user_id, u.current_points AS cached_points,
+			COALESCE(l.total, 0) AS ledger_points,
+			COALESCE(a.total, 0) + COALESCE(o.total, 0) AS expected_points`).
+		Joins("LEFT JOIN (SELECT user_id, SUM(delta) AS total FROM point_transactions GROUP BY user_id) l ON l.user_id = u.id").
+		Joins(`LEFT JOIN (
+			SELECT att.user_id, SUM(e.points_allocation) AS total
+			FROM (SELECT DISTINCT user_id, event_id FROM attendances) att
+			JOIN events e ON e.id = att.event_id AND e.deleted_at IS NULL
+			GROUP BY att.user_id
+		) a ON a.user_id = u.id`).
+		Joins("LEFT JOIN (SELECT user_id, SUM(delta) AS total FROM point_transactions WHERE reason NOT IN ? GROUP BY user_id) o ON o.user_id = u.id",
+			attendanceDerivedReasons).
+		Where("u.deleted_at IS NULL").
+		Order("u.id")
+	if userIDs != nil {
+		query = query.Where("u.id IN ?", userIDs)
+	}
+
+	var checks []balanceCheck
+	err := query.Scan(&checks).Error
+	return checks, err
+}
+
+// correctBalance resets a user's cached balance to their ledger and posts the correction bringing the ledger
+// to the expected points, returning the ledger entry of the correction
+func correctBalance(tx *gorm.DB, check balanceCheck, runID uint, actorID uint) (*uint, error) {
+	if check.CachedPoints != check.LedgerPoints {
+		if err := tx.Exec("UPDATE users SET current_points = ?, updated_at = ? WHERE id = ?",
+			check.LedgerPoints, time.Now(), check.UserID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	correction := check.ExpectedPoints - check.LedgerPoints
+	if correction == 0 {
+		// Only the cache was off, the badges may have followed the wrong balance
+		_, err := EvaluateAwards(tx, []uint{check.UserID})
+		return nil, err
+	}
+	transactions := []models.PointTransaction{{
+		UserID:  check.UserID,
+		Delta:   correction,
+		Reason:  string(models.PointReasonReconciliation),
+		ActorID: actorPointer(actorID),
+		Note:    fmt.Sprintf("Reconciliation run #%d, expected %d points but the ledger had %d", runID, check.ExpectedPoints, check.LedgerPoints),
+	}}
+	if _, err := PostPoints(tx, transactions); err != nil {
+		return nil, err
+	}
+	return &transactions[0].ID, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Length of the codes users show staff to pick up a reward
+const pickupCodeLength = 8
+
+var (
+	ErrRewardUnavailable    = errors.New("reward is not available")
+	ErrRewardOutOfStock     = errors.New("reward is out of stock")
+	ErrInsufficientPoints   = errors.New("not enough points")
+	ErrRedemptionNotPending = errors.New("redemption is no longer pending")
+)
+
+// RedeemReward spends a user's points on a reward: the balance is debited through the ledger, an item is taken
+// from the stock and the redemption gets a pickup code. Everything happens in one transaction with the reward
+// and the user locked, so two redemptions can't both take the last item or spend the same points.
+func RedeemReward(db *gorm.DB, rewardID, userID uint) (models.Redemption, error) {
+	var redemption models.Redemption
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var reward models.Reward
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, rewardID).Error; err != nil {
+			return err
+		}
+		if !reward.AvailableAt(time.Now()) {
+			return ErrRewardUnavailable
+		}
+		if reward.Stock != nil && *reward.Stock == 0 {
+			return ErrRewardOutOfStock
+		}
+
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "current_points").
+			First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.CurrentPoints < reward.Cost {
+			return ErrInsufficientPoints
+		}
+
+		debit := []models.PointTransaction{{
+			UserID:  userID,
+			Delta:   -reward.Cost,
+			Reason:  string(models.PointReasonRedemption),
+			ActorID: &userID,
+			Note:    fmt.Sprintf("Redeemed %q", reward.Name),
+		}}
+		if _, err := PostPoints(tx, debit); err != nil {
+			return err
+		}
+		if reward.Stock != nil {
+			if err := tx.Model(&reward).Update("stock", gorm.Expr("stock - 1")).Error; err != nil {
+				return err
+			}
+			*reward.Stock--
+		}
+
+		code, err := utils.GenerateReadableCode(pickupCodeLength)
+		if err != nil {
+			return err
+		}
+		redemption = models.Redemption{
+			RewardID:           reward.ID,
+			UserID:             userID,
+			Cost:               reward.Cost,
+			Status:             string(models.RedemptionPending),
+			PickupCode:         code,
+			DebitTransactionID: debit[0].ID,
+		}
+		if err := tx.Create(&redemption).Error; err != nil {
+			return err
+		}
+		redemption.Reward = &reward
+		return nil
+	})
+	return redemption, err
+}
+
+// FulfillRedemption marks the pending redemption with a pickup code as handed over by a staff member
+func FulfillRedemption(db *gorm.DB, pickupCode string, actorID uint) (models.Redemption, error) {
+	var redemption models.Redemption
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("pickup_code = ?", pickupCode).
+			First(&redemption).Error; err != nil {
+			return err
+		}
+		if redemption.Status != string(models.RedemptionPending) {
+			return ErrRedemptionNotPending
+		}
+
+		now := time.Now()
+		redemption.Status = string(models.RedemptionFulfilled)
+		redemption.FulfilledAt = &now
+		redemption.FulfilledByID = &actorID
+		return tx.Model(&redemption).Select("status", "fulfilled_at", "fulfilled_by_id").Updates(&redemption).Error
+	})
+	return redemption, err
+}
+
+// CancelRedemption cancels a pending redemption: the points come back through a refund in the ledger and the
+// item goes back in stock
+func CancelRedemption(db *gorm.DB, redemptionID, actorID uint) (models.Redemption, error) {
+	var redemption models.Redemption
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&redemption, redemptionID).Error; err != nil {
+			return err
+		}
+		if redemption.Status != string(models.RedemptionPending) {
+			return ErrRedemptionNotPending
+		}
+
+		var reward models.Reward
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, redemption.RewardID).Error; err != nil {
+			return err
+		}
+		refund := []models.PointTransaction{{
+			UserID:  redemption.UserID,
+			Delta:   redemption.Cost,
+			Reason:  string(models.PointReasonRedemptionRefund),
+			ActorID: actorPointer(actorID),
+			Note:    fmt.Sprintf("Redemption of %q was cancelled", reward.Name),
+		}}
+		if _, err := PostPoints(tx, refund); err != nil {
+			return err
+		}
+		if reward.Stock != nil {
+			if err := tx.Model(&reward).Update("stock", gorm.Expr("stock + 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		redemption.Status = string(models.RedemptionCancelled)
+		redemption.RefundTransactionID = &refund[0].ID
+		redemption.CancelledAt = &now
+		redemption.CancelledByID = actorPointer(actorID)
+		return tx.Model(&redemption).Select("status", "refund_transaction_id", "cancelled_at", "cancelled_by_id").
+			Updates(&redemption).Error
+	})
+	return redemption, err
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/rules"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// extendStreaks counts new attendances (one per user) towards the streaks of their users. Attendance in the
+// latest week attended or the one after only touches the stored streak, anything else (backdated attendance,
+// users without a streak yet) has the streak recomputed.
+func extendStreaks(tx *gorm.DB, attendances []models.Attendance) error {
+	userIDs := make([]uint, 0, len(attendances))
+	at := make(map[uint]time.Time, len(attendances))
+	for _, attendance := range attendances {
+		userIDs = append(userIDs, attendance.UserID)
+		at[attendance.UserID] = attendance.ScannedTime
+	}
+
+	var streaks []models.UserStreak
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id IN ?", userIDs).
+		Find(&streaks).Error; err != nil {
+		return err
+	}
+	found := make(map[uint]bool, len(streaks))
+	for _, streak := range streaks {
+		found[streak.UserID] = true
+	}
+	var recompute []uint
+	for _, userID := range userIDs {
+		if !found[userID] {
+			recompute = append(recompute, userID)
+		}
+	}
+
+	for _, streak := range streaks {
+		week := rules.WeekStart(at[streak.UserID])
+		var last time.Time
+		if streak.LastWeek != nil {
+			last = rules.WeekStart(*streak.LastWeek)
+		}
+		switch {
+		case streak.LastWeek == nil || week.After(last.AddDate(0, 0, 7)):
+			// First attendance since all of it was removed, or the previous streak was broken
+			streak.CurrentWeeks = 1
+			streak.CurrentStart = &week
+		case week.Equal(last.AddDate(0, 0, 7)):
+			streak.CurrentWeeks++
+		case week.Equal(last):
+			continue
+		default:
+			recompute = append(recompute, streak.UserID)
+			continue
+		}
+		streak.LastWeek = &week
+		if streak.CurrentWeeks > streak.LongestWeeks {
+			streak.LongestWeeks = streak.CurrentWeeks
+			streak.LongestStart = streak.CurrentStart
+		}
+		if err := tx.Save(&streak).Error; err != nil {
+			return err
+		}
+	}
+
+	return RecomputeStreaks(tx, recompute)
+}
+
+// RecomputeStreaks works out the streaks of users from their attendance, for changes that can break a streak
+// in the middle (removed attendance, deleted or restored events). Attendance at deleted events doesn't count.
+func RecomputeStreaks(tx *gorm.DB, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var attendances []struct {
+		UserID      uint
+		ScannedTime time.Time
+	}
+	if err := tx.Table("attendances").
+		Select("attendances.user_id, attendances.scanned_time").
+		Joins("JOIN events ON events.id = attendances.event_id AND events.deleted_at IS NULL").
+		Where("attendances.user_id IN ?", userIDs).
+		Scan(&attendances).Error; err != nil {
+		return err
+	}
+	weeks := make(map[uint]map[time.Time]bool, len(userIDs))
+	for _, attendance := range attendances {
+		if weeks[attendance.UserID] == nil {
+			weeks[attendance.UserID] = make(map[time.Time]bool)
+		}
+		weeks[attendance.UserID][rules.WeekStart(attendance.ScannedTime)] = true
+	}
+
+	streaks := make([]models.UserStreak, 0, len(userIDs))
+	seen := make(map[uint]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if !seen[userID] {
+			seen[userID] = true
+			streaks = append(streaks, streakFromWeeks(userID, weeks[userID]))
+		}
+	}
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(&streaks, 100).Error
+}
+
+// RecomputeEventStreaks recomputes the streaks of everyone who attended an event, for when it is deleted or
+// restored
+func RecomputeEventStreaks(tx *gorm.DB, event models.Event) error {
+	var userIDs []uint
+	if err := tx.Model(&models.Attendance{}).Where("event_id = ?", event.ID).
+		Distinct().Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	return RecomputeStreaks(tx, userIDs)
+}
+
+// BackfillStreaks records the streaks of users who attended events before streaks were tracked, and returns
+// how many were recorded
+func BackfillStreaks(db *gorm.DB) (int, error) {
+	var userIDs []uint
+	if err := db.Model(&models.Attendance{}).
+		Where("NOT EXISTS (SELECT 1 FROM user_streaks WHERE user_streaks.user_id = attendances.user_id)").
+		Distinct().Pluck("user_id", &userIDs).Error; err != nil || len(userIDs) == 0 {
+		return 0, err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return RecomputeStreaks(tx, userIDs)
+	})
+	return len(userIDs), err
+}
+
+// streakFromWeeks builds a user's streak from the weeks they attended, the same way the weekly_streak award
+// rule counts them
+func streakFromWeeks(userID uint, weeks map[time.Time]bool) models.UserStreak {
+	streak := models.UserStreak{UserID: userID}
+	sorted := make([]time.Time, 0, len(weeks))
+	for week := range weeks {
+		sorted = append(sorted, week)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	for i := range sorted {
+		week := sorted[i]
+		if i > 0 && week.Equal(sorted[i-1].AddDate(0, 0, 7)) {
+			streak.CurrentWeeks++
+		} else {
+			streak.CurrentWeeks = 1
+			streak.CurrentStart = &week
+		}
+		streak.LastWeek = &week
+		if streak.CurrentWeeks > streak.LongestWeeks {
+			streak.LongestWeeks = streak.CurrentWeeks
+			streak.LongestStart = streak.CurrentStart
+		}
+	}
+	return streak
+}
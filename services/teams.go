@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAlreadyMember        = errors.New("user is already a member of the team")
+	ErrExclusiveTeam        = errors.New("user already belongs to a team of this kind")
+	ErrNotMember            = errors.New("user is not a member of the team")
+	ErrCompetitionDeclared  = errors.New("competition winner was already declared")
+	ErrCompetitionNotEnded  = errors.New("competition has not ended yet")
+	ErrCompetitionTied      = errors.New("competition is tied")
+	ErrCompetitionNoTeam    = errors.New("team does not take part in the competition")
+	ErrCompetitionNoEntries = errors.New("competition has no teams")
+)
+
+// TeamScore is a team's points over a period, teams with the same points share a rank (dense ranking)
+type TeamScore struct {
+	Rank    int    `json:"rank"`
+	TeamID  uint   `json:"team_id"`
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Members int    `json:"members"` // Current members
+	Points  int    `json:"points"`
+}
+
+// TeamScoreFilter picks the teams to score and the period their members' attendance counts in
+type TeamScoreFilter struct {
+	TeamIDs []uint     // Only these teams, all of them when empty
+	Kind    string     // Only teams of this kind
+	From    *time.Time // Attendance from this time on
+	To      *time.Time // Attendance before this time
+}
+
+// TeamScores ranks teams by the points of the events their members attended while they were members. Points
+// follow the events' current allocation and deleted events don't count, like user balances.
+func TeamScores(db *gorm.DB, filter TeamScoreFilter) ([]TeamScore, error) {
+	attended := "att.user_id = tm.user_id AND att.scanned_time >= tm.joined_at AND (tm.left_at IS NULL OR att.scanned_time < tm.left_at)"
+	var vars []interface{}
+	if filter.From != nil {
+		attended += " AND att.scanned_time >= ?"
+		vars = append(vars, *filter.From)
+	}
+	if filter.To != nil {
+		attended += " AND att.scanned_time < ?"
+		vars = append(vars, *filter.To)
+	}
+
+	points := "COALESCE(SUM(e.points_allocation), 0)"
+	query := db.Model(&models.Team{}).
+		Select(`teams.id AS team_id, teams.name, teams.kind,
+			(SELECT COUNT(*) FROM team_memberships cm JOIN users cu ON cu.id = cm.user_id AND cu.deleted_at IS NULL
+				WHERE cm.team_id = teams.id AND cm.left_at IS NULL) AS members,
+			`+points+` AS points, DENSE_RANK() OVER (ORDER BY `+points+` DESC) AS rank`).
+		Joins("LEFT JOIN team_memberships tm ON tm.team_id = teams.id").
+		Joins("LEFT JOIN attendances att ON "+attended, vars...).
+		Joins("LEFT JOIN events e ON e.id = att.event_id AND e.deleted_at IS NULL").
+		Group("teams.id")
+	if len(filter.TeamIDs) > 0 {
+		query = query.Where("teams.id IN ?", filter.TeamIDs)
+	}
+	if filter.Kind != "" {
+		query = query.Where("teams.kind = ?", filter.Kind)
+	}
+
+	scores := []TeamScore{}
+	err := query.Order("rank, teams.name, teams.id").Scan(&scores).Error
+	return scores, err
+}
+
+// JoinTeam makes a user a member of a team. Users can only be in one team of an exclusive kind (houses) at a
+// time, the user row is locked so two joins can't both pass that check.
+func JoinTeam(db *gorm.DB, teamID, userID uint) (models.TeamMembership, error) {
+	var membership models.TeamMembership
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var team models.Team
+		if err := tx.First(&team, teamID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+
+		var current []models.Team
+		if err := tx.Joins("JOIN team_memberships ON team_memberships.team_id = teams.id AND team_memberships.left_at IS NULL").
+			Where("team_memberships.user_id = ? AND teams.kind = ?", userID, team.Kind).
+			Find(&current).Error; err != nil {
+			return err
+		}
+		for _, other := range current {
+			if other.ID == team.ID {
+				return ErrAlreadyMember
+			}
+		}
+		if len(current) > 0 && models.TeamKind(team.Kind).Exclusive() {
+			return ErrExclusiveTeam
+		}
+
+		membership = models.TeamMembership{TeamID: team.ID, UserID: userID, JoinedAt: time.Now()}
+		return tx.Create(&membership).Error
+	})
+	return membership, err
+}
+
+// LeaveTeam ends a user's membership of a team, the team keeps what they earned while they were a member
+func LeaveTeam(db *gorm.DB, teamID, userID uint) error {
+	result := db.Model(&models.TeamMembership{}).
+		Where("team_id = ? AND user_id = ? AND left_at IS NULL", teamID, userID).
+		Update("left_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// CompetitionScores ranks the teams of a competition by what their members earned during it
+func CompetitionScores(db *gorm.DB, competition models.Competition) ([]TeamScore, error) {
+	var teamIDs []uint
+	if err := db.Table("competition_teams").Where("competition_id = ?", competition.ID).
+		Pluck("team_id", &teamIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(teamIDs) == 0 {
+		return []TeamScore{}, nil
+	}
+	return TeamScores(db, TeamScoreFilter{TeamIDs: teamIDs, From: &competition.StartsAt, To: &competition.EndsAt})
+}
+
+// DeclareWinner declares the winner of a competition that has ended: the given team, or the top scorer when
+// teamID is 0. Ties have to be settled by naming the winner. actorID is 0 when declared automatically.
+func DeclareWinner(db *gorm.DB, competitionID, teamID, actorID uint) (models.Competition, error) {
+	var competition models.Competition
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Lock the competition so the winner can't be declared twice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&competition, competitionID).Error; err != nil {
+			return err
+		}
+		if competition.DeclaredAt != nil {
+			return ErrCompetitionDeclared
+		}
+		if time.Now().Before(competition.EndsAt) {
+			return ErrCompetitionNotEnded
+		}
+
+		scores, err := CompetitionScores(tx, competition)
+		if err != nil {
+			return err
+		}
+		if len(scores) == 0 {
+			return ErrCompetitionNoEntries
+		}
+		if teamID == 0 {
+			if len(scores) > 1 && scores[1].Rank == scores[0].Rank {
+				return ErrCompetitionTied
+			}
+			teamID = scores[0].TeamID
+		}
+		entered := false
+		for _, score := range scores {
+			entered = entered || score.TeamID == teamID
+		}
+		if !entered {
+			return ErrCompetitionNoTeam
+		}
+
+		now := time.Now()
+		competition.WinnerTeamID = &teamID
+		competition.DeclaredAt = &now
+		competition.DeclaredByID = actorPointer(actorID)
+		return tx.Model(&competition).Select("winner_team_id", "declared_at", "declared_by_id").
+			Updates(&competition).Error
+	})
+	return competition, err
+}
+
+// DeclareEndedCompetitions declares the winner of every competition that has ended, leaving ties (and
+// competitions without teams) for an admin to settle, and returns how many were declared
+func DeclareEndedCompetitions(db *gorm.DB) (int, error) {
+	var competitionIDs []uint
+	if err := db.Model(&models.Competition{}).Where("declared_at IS NULL AND ends_at <= ?", time.Now()).
+		Pluck("id", &competitionIDs).Error; err != nil {
+		return 0, err
+	}
+
+	declared := 0
+	for _, competitionID := range competitionIDs {
+		_, err := DeclareWinner(db, competitionID, 0, 0)
+		switch {
+		case err == nil:
+			declared++
+		case errors.Is(err, ErrCompetitionTied), errors.Is(err, ErrCompetitionNoEntries), errors.Is(err, ErrCompetitionDeclared):
+		default:
+			return declared, err
+		}
+	}
+	return declared, nil
+}
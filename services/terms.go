@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// termAttributedAt is the moment a ledger entry counts towards a term: when its event started, or when the
+// entry was posted if it isn't about a scheduled event. It needs events LEFT JOINed on the entry's event.
+const termAttributedAt = "COALESCE(events.start_time, point_transactions.created_at)"
+
+// termlessReasons are the ledger reasons that don't count towards any term: spending, and the opening balances
+// and reconciliation corrections that restate points earned over many terms at the moment they were posted
+var termlessReasons = append([]models.PointReason{models.PointReasonOpeningBalance, models.PointReasonReconciliation},
+	spendingReasons...)
+
+var (
+	ErrTermClosed   = errors.New("term is already closed")
+	ErrTermNotEnded = errors.New("term has not ended yet")
+)
+
+// TermBalance is how many points a user earned in a term
+type TermBalance struct {
+	TermID   uint      `json:"term_id"`
+	Name     string    `json:"name"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Points   int       `json:"points"`
+}
+
+// CurrentTerm returns the term in progress, nil when there is none
+func CurrentTerm(db *gorm.DB) (*models.Term, error) {
+	var term models.Term
+	now := time.Now()
+	err := db.Where("starts_at <= ? AND ends_at > ?", now, now).First(&term).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &term, nil
+}
+
+// UserTermBalances returns what a user earned in every term, most recent first. Entries are attributed to
+// terms when they are read, so terms added later pick up the points of their events too.
+func UserTermBalances(db *gorm.DB, userID uint) ([]TermBalance, error) {
+	ledger := db.Model(&models.PointTransaction{}).
+		Select("point_transactions.delta, "+termAttributedAt+" AS attributed_at").
+		Joins("LEFT JOIN events ON events.id = point_transactions.event_id").
+		Where("point_transactions.user_id = ? AND point_transactions.reason NOT IN ?", userID, termlessReasons)
+
+	balances := []TermBalance{}
+	err := db.Model(&models.Term{}).
+		Select("terms.id AS term_id, terms.name, terms.starts_at, terms.ends_at, COALESCE(SUM(ledger.delta), 0) AS points").
+		Joins("LEFT JOIN (?) AS ledger ON ledger.attributed_at >= terms.starts_at AND ledger.attributed_at < terms.ends_at", ledger).
+		Group("terms.id").
+		Order("terms.starts_at DESC").
+		Scan(&balances).Error
+	return balances, err
+}
+
+// CloseTerm records the final standings of a term that has ended and marks it closed, returning the closed
+// term and how many standings were recorded. Standings rank the same users as the term leaderboard, those with
+// no points in the term are left out.
+func CloseTerm(db *gorm.DB, termID uint, actorID uint) (models.Term, int, error) {
+	var term models.Term
+	recorded := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Lock the term so it can't be closed twice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&term, termID).Error; err != nil {
+			return err
+		}
+		if term.ClosedAt != nil {
+			return ErrTermClosed
+		}
+		if time.Now().Before(term.EndsAt) {
+			return ErrTermNotEnded
+		}
+
+		board, err := computeLeaderboard(tx, LeaderboardFilter{TermID: &term.ID})
+		if err != nil {
+			return err
+		}
+		standings := make([]models.TermStanding, 0, len(board.Entries))
+		for _, entry := range board.Entries {
+			if entry.Points == 0 {
+				continue
+			}
+			standings = append(standings, models.TermStanding{
+				TermID: term.ID,
+				UserID: entry.UserID,
+				Rank:   entry.Rank,
+				Points: entry.Points,
+			})
+		}
+		if len(standings) > 0 {
+			if err := tx.CreateInBatches(standings, 500).Error; err != nil {
+				return err
+			}
+		}
+		recorded = len(standings)
+
+		now := time.Now()
+		term.ClosedAt = &now
+		term.ClosedByID = actorPointer(actorID)
+		return tx.Model(&term).Select("closed_at", "closed_by_id").Updates(&term).Error
+	})
+	return term, recorded, err
+}
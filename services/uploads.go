@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"github.com/open-cmuq/passport-backend/storage"
+	"github.com/open-cmuq/passport-backend/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// Thumbnails fit in a square of this many pixels
+	ThumbnailSize = 320
+	// Uploads are limited to this size unless UPLOAD_MAX_BYTES says otherwise
+	defaultUploadMaxBytes = 5 << 20
+	// Files younger than this are never considered orphaned, so an upload isn't removed
+	// before the request that made it had a chance to point at it
+	orphanGracePeriod = time.Hour
+)
+
+// StoredImage is where an uploaded image and its thumbnail can be fetched from
+type StoredImage struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// UploadMaxBytes is the largest accepted upload, configured with UPLOAD_MAX_BYTES
+func UploadMaxBytes() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_BYTES"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return defaultUploadMaxBytes
+}
+
+// StoreImage validates an uploaded image, stores it along with a thumbnail under prefix and
+// tracks both files so they are cleaned up once nothing points to them anymore.
+// It returns utils.ErrUnsupportedImage or utils.ErrImageTooLarge for bad uploads.
+func StoreImage(ctx context.Context, db *gorm.DB, prefix string, data []byte, uploaderID uint) (StoredImage, error) {
+	img, contentType, ext, err := utils.DecodeImage(data)
+	if err != nil {
+		return StoredImage{}, err
+	}
+	thumbnail, thumbnailType, thumbnailExt, err := utils.EncodeImage(utils.Thumbnail(img, ThumbnailSize), contentType)
+	if err != nil {
+		return StoredImage{}, err
+	}
+
+	name, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return StoredImage{}, err
+	}
+
+	var uploader *uint
+	if uploaderID != 0 {
+		uploader = &uploaderID
+	}
+	blobs := []models.Blob{
+		{Key: prefix + "/" + name + ext, ContentType: contentType, Size: int64(len(data)), UploadedByID: uploader},
+		{Key: prefix + "/" + name + "_thumb" + thumbnailExt, ContentType: thumbnailType, Size: int64(len(thumbnail)), UploadedByID: uploader},
+	}
+	contents := [][]byte{data, thumbnail}
+
+	// Track the files before writing them so a failure half way never leaves untracked files behind
+	for i := range blobs {
+		blobs[i].URL = storage.Store.URL(blobs[i].Key)
+	}
+	if err := db.Create(&blobs).Error; err != nil {
+		return StoredImage{}, err
+	}
+	for i, blob := range blobs {
+		if err := storage.Store.Put(ctx, blob.Key, contents[i], blob.ContentType); err != nil {
+			return StoredImage{}, err
+		}
+	}
+
+	return StoredImage{URL: blobs[0].URL, ThumbnailURL: blobs[1].URL}, nil
+}
+
+// CleanupOrphanedBlobs deletes the uploaded files no event, award, user, template, series, reward or team points
+// to anymore (replaced images, purged events, ...) and returns how many were deleted
+func CleanupOrphanedBlobs(ctx context.Context, db *gorm.DB) (int, error) {
+	var orphans []models.Blob
+	err := db.Raw(`
+		SELECT b.* FROM blobs b
+		WHERE b.created_at < ?
+		  AND NOT EXISTS (SELECT 1 FROM events e WHERE e.image_url = b.url OR e.thumbnail_url = b.url)
+		  AND NOT EXISTS (SELECT 1 FROM awards a WHERE a.icon_url = b.url OR a.icon_thumbnail_url = b.url)
+		  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.photo_url = b.url OR u.photo_thumbnail_url = b.url)
+		  AND NOT EXISTS (SELECT 1 FROM event_templates t WHERE t.image_url = b.url)
+		  AND NOT EXISTS (SELECT 1 FROM event_series s WHERE s.image_url = b.url)
+		  AND NOT EXISTS (SELECT 1 FROM rewards r WHERE r.image_url = b.url)
+		  AND NOT EXISTS (SELECT 1 FROM teams tm WHERE tm.image_url = b.url)
+		ORDER BY b.id
+		LIMIT 500
+	`, time.Now().Add(-orphanGracePeriod)).Scan(&orphans).Error
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, blob := range orphans {
+		if err := storage.Store.Delete(ctx, blob.Key); err != nil {
+			// Keep the row so the file is retried on the next run
+			log.Printf("Failed to delete orphaned blob %s: %v", blob.Key, err)
+			continue
+		}
+		if err := db.Delete(&blob).Error; err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/open-cmuq/passport-backend/models"
+	"gorm.io/gorm"
+)
+
+// Locations are only linked to a venue when they are at least this similar to its name or an alias
+const venueMatchThreshold = 0.85
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// locationAbbreviations expands the shorthands people use when typing locations
+var locationAbbreviations = map[string]string{
+	"ec":     "education city",
+	"bldg":   "building",
+	"bld":    "building",
+	"rm":     "room",
+	"ctr":    "center",
+	"centre": "center",
+	"st":     "street",
+	"univ":   "university",
+}
+
+// LocationMatch is the venue a free-text location was (or would be) linked to
+type LocationMatch struct {
+	Location   string  `json:"location"`
+	EventCount int64   `json:"event_count"`
+	VenueID    *uint   `json:"venue_id"` // nil when no venue is similar enough
+	VenueName  string  `json:"venue_name,omitempty"`
+	Score      float64 `json:"score"`
+	Applied    bool    `json:"applied"`
+}
+
+// MapEventLocations links the events without a venue to the venue their free-text location matches best.
+// With dryRun nothing is changed and the matches that would be applied are returned.
+func MapEventLocations(db *gorm.DB, dryRun bool) ([]LocationMatch, error) {
+	var venues []models.Venue
+	if err := db.Find(&venues).Error; err != nil {
+		return nil, err
+	}
+
+	var locations []struct {
+		Location   string
+		EventCount int64
+	}
+	if err := db.Model(&models.Event{}).
+		Select("location, COUNT(*) AS event_count").
+		Where("venue_id IS NULL AND TRIM(location) <> ''").
+		Group("location").
+		Order("event_count DESC, location").
+		Scan(&locations).Error; err != nil {
+		return nil, err
+	}
+
+	matches := make([]LocationMatch, 0, len(locations))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, location := range locations {
+			match := LocationMatch{Location: location.Location, EventCount: location.EventCount}
+			if venue, score := bestVenueMatch(location.Location, venues); venue != nil {
+				match.Score = score
+				if score >= venueMatchThreshold {
+					match.VenueID = &venue.ID
+					match.VenueName = venue.Name
+				}
+			}
+
+			if match.VenueID != nil && !dryRun {
+				if err := tx.Model(&models.Event{}).
+					Where("venue_id IS NULL AND location = ?", location.Location).
+					Update("venue_id", *match.VenueID).Error; err != nil {
+					return err
+				}
+				match.Applied = true
+			}
+			matches = append(matches, match)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// bestVenueMatch returns the venue whose name or aliases are the most similar to a location
+func bestVenueMatch(location string, venues []models.Venue) (*models.Venue, float64) {
+	normalized := normalizeLocation(location)
+	var best *models.Venue
+	bestScore := 0.0
+	for i := range venues {
+		names := append([]string{venues[i].Name}, venues[i].Aliases...)
+		for _, name := range names {
+			if score := locationSimilarity(normalized, normalizeLocation(name)); score > bestScore {
+				best, bestScore = &venues[i], score
+			}
+		}
+	}
+	return best, bestScore
+}
+
+// normalizeLocation lowercases a location, drops punctuation and expands abbreviations
+func normalizeLocation(location string) string {
+	words := strings.Fields(nonAlphanumeric.ReplaceAllString(strings.ToLower(location), " "))
+	for i, word := range words {
+		if expanded, ok := locationAbbreviations[word]; ok {
+			words[i] = expanded
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// locationSimilarity scores two normalized locations between 0 and 1. It takes the better of the edit
+// distance ratio (catches typos) and the overlap of their sorted words (catches reordered words). Numbers
+// have to match exactly, Room 101 and Room 104 are different rooms however similar they look.
+func locationSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	if !sameNumbers(a, b) {
+		return 0
+	}
+	return max(editSimilarity(a, b), editSimilarity(sortedWords(a), sortedWords(b)), wordOverlap(a, b))
+}
+
+// editSimilarity is 1 minus the Levenshtein distance relative to the longer string
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+// wordOverlap is the Dice coefficient of the words of two locations
+func wordOverlap(a, b string) float64 {
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	set := make(map[string]bool, len(wordsB))
+	for _, word := range wordsB {
+		set[word] = true
+	}
+	shared := 0
+	for _, word := range wordsA {
+		if set[word] {
+			shared++
+			delete(set, word)
+		}
+	}
+	return 2 * float64(shared) / float64(len(wordsA)+len(wordsB))
+}
+
+// sameNumbers reports whether two locations have the same words containing digits (room numbers, floors...)
+func sameNumbers(a, b string) bool {
+	numbers := func(s string) string {
+		var words []string
+		for _, word := range strings.Fields(s) {
+			if strings.ContainsAny(word, "0123456789") {
+				words = append(words, word)
+			}
+		}
+		sort.Strings(words)
+		return strings.Join(words, " ")
+	}
+	return numbers(a) == numbers(b)
+}
+
+func sortedWords(s string) string {
+	words := strings.Fields(s)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
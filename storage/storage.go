@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+)
+
+// BlobStore stores uploaded files under a key and tells where clients can fetch them
+type BlobStore interface {
+	// Put stores data under key, replacing whatever was stored there
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the data stored under key, deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of key
+	URL(key string) string
+}
+
+// Store is the blob store selected by STORAGE_BACKEND, set up by Init
+var Store BlobStore
+
+// Init sets up Store from the environment: STORAGE_BACKEND is "local" (default) or "s3"
+func Init() {
+	switch backend := strings.ToLower(os.Getenv("STORAGE_BACKEND")); backend {
+	case "", "local":
+		Store = &LocalStore{
+			Dir:     envOr("STORAGE_LOCAL_DIR", "./uploads"),
+			BaseURL: envOr("STORAGE_PUBLIC_URL", "/uploads"),
+		}
+	case "s3":
+		store, err := s3StoreFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		Store = store
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND %q, use local or s3", backend)
+	}
+	log.Println("Blob storage ready")
+}
+
+// s3StoreFromEnv configures an S3Store from the S3_* variables
+func s3StoreFromEnv() (*S3Store, error) {
+	store := &S3Store{
+		Endpoint:  envOr("S3_ENDPOINT", "https://s3.amazonaws.com"),
+		Region:    envOr("S3_REGION", "us-east-1"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		PathStyle: os.Getenv("S3_FORCE_PATH_STYLE") == "true",
+		PublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+	}
+	if store.Bucket == "" || store.AccessKey == "" || store.SecretKey == "" {
+		return nil, errors.New("S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for the s3 storage backend")
+	}
+	return store, nil
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
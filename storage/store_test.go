@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// testBlobStore checks the BlobStore contract, fetching blobs from their public URL like clients do
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	// Keys are unique to the run so a shared bucket never sees leftovers of an earlier one
+	prefix := fmt.Sprintf("contract-test/%d", time.Now().UnixNano())
+
+	fetch := func(t *testing.T, key string) (int, []byte, string) {
+		t.Helper()
+		resp, err := http.Get(store.URL(key))
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", store.URL(key), err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("reading %s failed: %v", store.URL(key), err)
+		}
+		return resp.StatusCode, body, resp.Header.Get("Content-Type")
+	}
+	put := func(t *testing.T, key string, data []byte, contentType string) {
+		t.Helper()
+		if err := store.Put(ctx, key, data, contentType); err != nil {
+			t.Fatalf("Put(%q) failed: %v", key, err)
+		}
+		t.Cleanup(func() { store.Delete(ctx, key) })
+	}
+
+	t.Run("put serves the data at its URL", func(t *testing.T) {
+		key := prefix + "/events/photo.png"
+		data := []byte("\x89PNG\r\n\x1a\nnot really a picture")
+		put(t, key, data, "image/png")
+
+		status, body, contentType := fetch(t, key)
+		if status != http.StatusOK || !bytes.Equal(body, data) {
+			t.Fatalf("GET = %d %q, want 200 %q", status, body, data)
+		}
+		if contentType != "image/png" {
+			t.Errorf("Content-Type = %q, want image/png", contentType)
+		}
+	})
+
+	t.Run("put replaces the data", func(t *testing.T) {
+		key := prefix + "/events/replaced.txt"
+		put(t, key, []byte("first version"), "text/plain")
+		put(t, key, []byte("second"), "text/plain")
+
+		if status, body, _ := fetch(t, key); status != http.StatusOK || string(body) != "second" {
+			t.Fatalf("GET = %d %q, want 200 %q", status, body, "second")
+		}
+	})
+
+	t.Run("put stores empty data", func(t *testing.T) {
+		key := prefix + "/empty.txt"
+		put(t, key, nil, "text/plain")
+
+		if status, body, _ := fetch(t, key); status != http.StatusOK || len(body) != 0 {
+			t.Fatalf("GET = %d %q, want 200 and no data", status, body)
+		}
+	})
+
+	t.Run("delete stops serving the data", func(t *testing.T) {
+		key := prefix + "/deleted.txt"
+		put(t, key, []byte("gone soon"), "text/plain")
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		if status, _, _ := fetch(t, key); status == http.StatusOK {
+			t.Fatal("deleted blob is still served")
+		}
+	})
+
+	t.Run("deleting a missing key succeeds", func(t *testing.T) {
+		if err := store.Delete(ctx, prefix+"/never-stored.txt"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	})
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	// Serve the directory the way the router does
+	server := httptest.NewServer(http.StripPrefix("/uploads", http.FileServer(http.Dir(dir))))
+	defer server.Close()
+
+	testBlobStore(t, &LocalStore{Dir: dir, BaseURL: server.URL + "/uploads/"})
+}
+
+func TestLocalStoreRefusesEscapingKeys(t *testing.T) {
+	dir := t.TempDir()
+	store := &LocalStore{Dir: dir + "/uploads", BaseURL: "/uploads"}
+
+	for _, key := range []string{"", "/", "../outside.txt", "events/../../outside.txt"} {
+		if err := store.Put(context.Background(), key, []byte("data"), "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", key)
+		}
+	}
+	if _, err := os.Stat(dir + "/outside.txt"); err == nil {
+		t.Error("a blob was written outside the store directory")
+	}
+}
+
+// TestS3Store runs against the bucket configured by the S3_* variables, e.g. the MinIO of docker-compose with
+// the values of .env.example
+func TestS3Store(t *testing.T) {
+	if os.Getenv("S3_ENDPOINT") == "" {
+		t.Skip("S3_ENDPOINT is not set")
+	}
+	store, err := s3StoreFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBlobStore(t, store)
+}
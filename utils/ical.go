@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const icalTimeFormat = "20060102T150405Z"
+
+// CalendarEvent is a single VEVENT of an iCalendar (RFC 5545) feed
+type CalendarEvent struct {
+	UID          string    // Stable across updates so subscribed calendars replace instead of duplicate
+	Sequence     int       // Revision number, must increase whenever the event changes
+	Summary      string    // Event title
+	Description  string    // Free text description
+	Location     string    // Free text location
+	Start        time.Time // DTSTART
+	End          time.Time // DTEND
+	Status       string    // CONFIRMED, TENTATIVE or CANCELLED
+	LastModified time.Time // Zero when unknown
+}
+
+// BuildCalendar renders events as an iCalendar document with CRLF line endings and folded lines
+func BuildCalendar(name string, events []CalendarEvent) string {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		writeFolded(&b, fmt.Sprintf(format, args...))
+	}
+
+	now := time.Now().UTC().Format(icalTimeFormat)
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//CMU-Q//EcoCampus Passport//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:%s", escapeText(name))
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line("UID:%s", event.UID)
+		line("SEQUENCE:%d", event.Sequence)
+		line("DTSTAMP:%s", now)
+		line("DTSTART:%s", event.Start.UTC().Format(icalTimeFormat))
+		line("DTEND:%s", event.End.UTC().Format(icalTimeFormat))
+		if !event.LastModified.IsZero() {
+			line("LAST-MODIFIED:%s", event.LastModified.UTC().Format(icalTimeFormat))
+		}
+		line("SUMMARY:%s", escapeText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:%s", escapeText(event.Description))
+		}
+		if event.Location != "" {
+			line("LOCATION:%s", escapeText(event.Location))
+		}
+		if event.Status != "" {
+			line("STATUS:%s", event.Status)
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	return b.String()
+}
+
+// escapeText escapes a TEXT property value as required by RFC 5545 section 3.3.11
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return replacer.Replace(value)
+}
+
+// writeFolded writes a content line, folding it at 75 octets without splitting UTF-8 characters
+func writeFolded(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // The leading space of continuation lines counts towards the limit
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
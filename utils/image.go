@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Uploaded images larger than this are refused before decoding so a small file can't
+// expand into gigabytes of pixels
+const MaxImagePixels = 40_000_000
+
+var (
+	ErrUnsupportedImage = errors.New("only JPEG, PNG and GIF images are supported")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+)
+
+// imageExtensions maps the supported content types to file extensions
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// DecodeImage sniffs the content type of an upload from its bytes (the client supplied type is not
+// trusted) and decodes it, returning the image, its content type and file extension
+func DecodeImage(data []byte) (image.Image, string, string, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, "", "", ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxImagePixels {
+		return nil, "", "", ErrImageTooLarge
+	}
+
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		img, err = gif.Decode(bytes.NewReader(data)) // First frame only
+	}
+	if err != nil {
+		return nil, "", "", ErrUnsupportedImage
+	}
+	return img, contentType, ext, nil
+}
+
+// Thumbnail scales an image down to fit in a size x size box, keeping its aspect ratio.
+// Every output pixel is the average of the source pixels it covers, which looks good when shrinking.
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= size && srcH <= size {
+		return img
+	}
+
+	dstW, dstH := size, size
+	if srcW > srcH {
+		dstH = max(1, srcH*size/srcW)
+	} else {
+		dstW = max(1, srcW*size/srcH)
+	}
+
+	// Sum the premultiplied channels of the source pixels falling into each output pixel
+	sums := make([][4]uint64, dstW*dstH)
+	counts := make([]uint64, dstW*dstH)
+	for y := 0; y < srcH; y++ {
+		row := (y * dstH / srcH) * dstW
+		for x := 0; x < srcW; x++ {
+			i := row + x*dstW/srcW
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			sums[i][0] += uint64(r)
+			sums[i][1] += uint64(g)
+			sums[i][2] += uint64(b)
+			sums[i][3] += uint64(a)
+			counts[i]++
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for i, sum := range sums {
+		n := counts[i]
+		if n == 0 {
+			continue
+		}
+		offset := (i/dstW)*dst.Stride + (i%dstW)*4
+		for channel := 0; channel < 4; channel++ {
+			dst.Pix[offset+channel] = uint8(sum[channel] / n >> 8)
+		}
+	}
+	return dst
+}
+
+// EncodeImage encodes an image as JPEG when it came from a JPEG and as PNG otherwise (to keep
+// transparency), returning the bytes, content type and file extension
+func EncodeImage(img image.Image, sourceContentType string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	if sourceContentType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/jpeg", ".jpg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "image/png", ".png", nil
+}
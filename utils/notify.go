@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"log"
+)
+
+// Mailer delivers notification emails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer is the default Mailer, no email service is set up yet so it only logs that a notification was due.
+// The recipient and body are left out of the log.
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("No mailer configured, skipped notification %q", subject)
+	return nil
+}
+
+// DefaultMailer delivers the notifications of SendEmail, replace it to integrate an email service like
+// SendGrid or AWS SES
+var DefaultMailer Mailer = LogMailer{}
+
+// SendEmail delivers a notification email in the background through DefaultMailer
+func SendEmail(to, subject, body string) {
+	mailer := DefaultMailer
+	go func() {
+		if err := mailer.Send(to, subject, body); err != nil {
+			log.Printf("Failed to send notification %q: %v", subject, err)
+		}
+	}()
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"encoding/json"
+)
+
+// Nullable is a JSON field that tells apart a missing key from an explicit null, which PATCH
+// requests need: a missing key leaves the value alone while null clears it
+type Nullable[T any] struct {
+	Set   bool // The key was present in the request body
+	Value *T   // nil when the key was sent as null
+}
+
+// UnmarshalJSON is only called when the key is present, so it always marks the field as set
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+	return nil
+}
+
+// Or returns the new value when the field was set, otherwise the current one
+func (n Nullable[T]) Or(current *T) *T {
+	if n.Set {
+		return n.Value
+	}
+	return current
+}
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits applied when materializing a recurrence rule so an open-ended rule can't create unbounded rows
+const (
+	MaxOccurrences    = 366
+	OccurrenceHorizon = 365 * 24 * time.Hour
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RRule is the subset of an RFC 5545 recurrence rule we support:
+// FREQ=DAILY|WEEKLY|MONTHLY with INTERVAL, COUNT, UNTIL and BYDAY (WEEKLY only)
+type RRule struct {
+	Freq     string         // DAILY, WEEKLY or MONTHLY
+	Interval int            // Defaults to 1
+	Count    int            // Total number of occurrences, 0 means unbounded
+	Until    *time.Time     // Last possible occurrence (inclusive), nil means unbounded
+	ByDay    []time.Weekday // Days of the week for WEEKLY rules, defaults to the weekday of DTSTART
+}
+
+// ParseRRule parses a recurrence rule such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"
+func ParseRRule(rule string) (*RRule, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return nil, errors.New("rrule is empty")
+	}
+
+	r := &RRule{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rrule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			if r.Freq != "DAILY" && r.Freq != "WEEKLY" && r.Freq != "MONTHLY" {
+				return nil, fmt.Errorf("unsupported FREQ %q, use DAILY, WEEKLY or MONTHLY", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval <= 0 {
+				return nil, errors.New("INTERVAL must be a positive integer")
+			}
+			r.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count <= 0 {
+				return nil, errors.New("COUNT must be a positive integer")
+			}
+			r.Count = count
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, errors.New("UNTIL must be a UTC date-time (20060102T150405Z) or date (20060102)")
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", code)
+				}
+				// A repeated day would expand to the same occurrence twice
+				if !slices.Contains(r.ByDay, day) {
+					r.ByDay = append(r.ByDay, day)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, errors.New("COUNT and UNTIL must not be used together")
+	}
+	if len(r.ByDay) > 0 && r.Freq != "WEEKLY" {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+
+	return r, nil
+}
+
+// String serializes the rule back into RFC 5545 form
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			for code, d := range weekdayCodes {
+				if d == day {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences expands the rule starting at dtstart, keeping the wall-clock time of dtstart.
+// Open-ended rules (without COUNT or UNTIL) stop at OccurrenceHorizon after dtstart, they aren't extended
+// later. Every rule stops at MaxOccurrences.
+func (r *RRule) Occurrences(dtstart time.Time) []time.Time {
+	var horizon *time.Time
+	if r.Until != nil {
+		horizon = r.Until
+	} else if r.Count == 0 {
+		end := dtstart.Add(OccurrenceHorizon)
+		horizon = &end
+	}
+
+	limit := MaxOccurrences
+	if r.Count > 0 && r.Count < limit {
+		limit = r.Count
+	}
+
+	var occurrences []time.Time
+	emit := func(t time.Time) bool {
+		if (horizon != nil && t.After(*horizon)) || len(occurrences) >= limit {
+			return false
+		}
+		if !t.Before(dtstart) {
+			occurrences = append(occurrences, t)
+		}
+		return true
+	}
+
+	switch r.Freq {
+	case "DAILY":
+		for i := 0; emit(dtstart.AddDate(0, 0, i*r.Interval)); i++ {
+		}
+	case "WEEKLY":
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+		// Weeks start on Monday (WKST=MO), so order the days accordingly
+		offsets := make([]int, 0, len(days))
+		for _, day := range days {
+			offsets = append(offsets, (int(day)+6)%7)
+		}
+		sort.Ints(offsets)
+
+		weekStart := dtstart.AddDate(0, 0, -((int(dtstart.Weekday()) + 6) % 7))
+		for week := 0; ; week += r.Interval {
+			for _, offset := range offsets {
+				if !emit(weekStart.AddDate(0, 0, week*7+offset)) {
+					return occurrences
+				}
+			}
+		}
+	case "MONTHLY":
+		// Months without the day of DTSTART (e.g. the 31st) are skipped as RFC 5545 requires
+		for i := 0; ; i += r.Interval {
+			year, month, _ := dtstart.Date()
+			first := time.Date(year, month+time.Month(i), 1, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+			candidate := first.AddDate(0, 0, dtstart.Day()-1)
+			if candidate.Month() != first.Month() {
+				if horizon != nil && first.After(*horizon) {
+					break
+				}
+				continue
+			}
+			if !emit(candidate) {
+				break
+			}
+		}
+	}
+
+	return occurrences
+}
+
+// ContainsTime checks whether a list of times contains the given instant, whatever their locations, so
+// occurrences can be matched against exception dates (EXDATE) stored in UTC
+func ContainsTime(times []time.Time, t time.Time) bool {
+	for _, candidate := range times {
+		if candidate.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// A date-only UNTIL includes the whole day
+	return t.Add(24*time.Hour - time.Second), nil
+}
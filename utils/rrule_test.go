@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want string // Serialized back, empty when the rule must be rejected
+	}{
+		{"daily", "FREQ=DAILY", "FREQ=DAILY"},
+		{"prefix and lowercase", "RRULE:freq=weekly;byday=mo,we;count=10", "FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE"},
+		{"repeated byday", "FREQ=WEEKLY;BYDAY=MO,WE,MO;COUNT=4", "FREQ=WEEKLY;COUNT=4;BYDAY=MO,WE"},
+		{"interval", "FREQ=MONTHLY;INTERVAL=3", "FREQ=MONTHLY;INTERVAL=3"},
+		{"until date-time", "FREQ=DAILY;UNTIL=20250305T100000Z", "FREQ=DAILY;UNTIL=20250305T100000Z"},
+		{"until date includes the day", "FREQ=DAILY;UNTIL=20250305", "FREQ=DAILY;UNTIL=20250305T235959Z"},
+		{"empty", "  ", ""},
+		{"without freq", "COUNT=3", ""},
+		{"unsupported freq", "FREQ=YEARLY", ""},
+		{"part without value", "FREQ", ""},
+		{"zero interval", "FREQ=DAILY;INTERVAL=0", ""},
+		{"negative count", "FREQ=DAILY;COUNT=-1", ""},
+		{"invalid until", "FREQ=DAILY;UNTIL=tomorrow", ""},
+		{"count and until", "FREQ=DAILY;COUNT=3;UNTIL=20250305", ""},
+		{"unknown day", "FREQ=WEEKLY;BYDAY=XX", ""},
+		{"byday with daily", "FREQ=DAILY;BYDAY=MO", ""},
+		{"unsupported part", "FREQ=MONTHLY;BYMONTHDAY=1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("ParseRRule(%q) = %s, want an error", tt.rule, rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRRule(%q) failed: %v", tt.rule, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("ParseRRule(%q).String() = %q, want %q", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	newYork := mustLoadLocation("America/New_York")
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []string
+	}{
+		{"count", "FREQ=DAILY;COUNT=3", mustParse("2025-03-03T10:00:00Z"),
+			[]string{"2025-03-03T10:00:00Z", "2025-03-04T10:00:00Z", "2025-03-05T10:00:00Z"}},
+		{"until is inclusive", "FREQ=DAILY;UNTIL=20250305T100000Z", mustParse("2025-03-03T10:00:00Z"),
+			[]string{"2025-03-03T10:00:00Z", "2025-03-04T10:00:00Z", "2025-03-05T10:00:00Z"}},
+		{"until date with interval", "FREQ=DAILY;INTERVAL=2;UNTIL=20250307", mustParse("2025-03-03T18:00:00Z"),
+			[]string{"2025-03-03T18:00:00Z", "2025-03-05T18:00:00Z", "2025-03-07T18:00:00Z"}},
+		{"until before dtstart", "FREQ=DAILY;UNTIL=20250301", mustParse("2025-03-03T10:00:00Z"), nil},
+		{"weekly on the day of dtstart", "FREQ=WEEKLY;COUNT=3", mustParse("2025-03-05T09:00:00Z"),
+			[]string{"2025-03-05T09:00:00Z", "2025-03-12T09:00:00Z", "2025-03-19T09:00:00Z"}},
+		// The Monday of the first week is before dtstart, so it doesn't count
+		{"byday with interval", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=5", mustParse("2025-03-05T09:00:00Z"),
+			[]string{"2025-03-05T09:00:00Z", "2025-03-17T09:00:00Z", "2025-03-19T09:00:00Z",
+				"2025-03-31T09:00:00Z", "2025-04-02T09:00:00Z"}},
+		{"byday out of order", "FREQ=WEEKLY;BYDAY=FR,TU;COUNT=3", mustParse("2025-03-04T09:00:00Z"),
+			[]string{"2025-03-04T09:00:00Z", "2025-03-07T09:00:00Z", "2025-03-11T09:00:00Z"}},
+		// Weeks start on Monday, so Sunday belongs to the week before the Monday that follows it
+		{"byday sunday with interval", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,MO;COUNT=4", mustParse("2025-03-03T09:00:00Z"),
+			[]string{"2025-03-03T09:00:00Z", "2025-03-09T09:00:00Z", "2025-03-17T09:00:00Z", "2025-03-23T09:00:00Z"}},
+		{"monthly skips short months", "FREQ=MONTHLY;COUNT=3", mustParse("2025-01-31T12:00:00Z"),
+			[]string{"2025-01-31T12:00:00Z", "2025-03-31T12:00:00Z", "2025-05-31T12:00:00Z"}},
+		{"monthly with interval", "FREQ=MONTHLY;INTERVAL=5;COUNT=3", mustParse("2025-10-15T12:00:00Z"),
+			[]string{"2025-10-15T12:00:00Z", "2026-03-15T12:00:00Z", "2026-08-15T12:00:00Z"}},
+		// New York moves to daylight saving time on 2025-03-09, the events stay at 9:00 local time
+		{"weekly across spring forward", "FREQ=WEEKLY;COUNT=3", time.Date(2025, 3, 2, 9, 0, 0, 0, newYork),
+			[]string{"2025-03-02T09:00:00-05:00", "2025-03-09T09:00:00-04:00", "2025-03-16T09:00:00-04:00"}},
+		{"daily across fall back", "FREQ=DAILY;COUNT=3", time.Date(2025, 11, 1, 9, 0, 0, 0, newYork),
+			[]string{"2025-11-01T09:00:00-04:00", "2025-11-02T09:00:00-05:00", "2025-11-03T09:00:00-05:00"}},
+		{"byday across spring forward", "FREQ=WEEKLY;BYDAY=SA,SU;COUNT=3", time.Date(2025, 3, 8, 18, 30, 0, 0, newYork),
+			[]string{"2025-03-08T18:30:00-05:00", "2025-03-09T18:30:00-04:00", "2025-03-15T18:30:00-04:00"}},
+		{"until across spring forward", "FREQ=DAILY;UNTIL=20250310T130000Z", time.Date(2025, 3, 8, 9, 0, 0, 0, newYork),
+			[]string{"2025-03-08T09:00:00-05:00", "2025-03-09T09:00:00-04:00", "2025-03-10T09:00:00-04:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q) failed: %v", tt.rule, err)
+			}
+			got := rule.Occurrences(tt.dtstart)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+			}
+			for i, want := range tt.want {
+				if !got[i].Equal(mustParse(want)) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].Format(time.RFC3339), want)
+				}
+				if got[i].Location() != tt.dtstart.Location() {
+					t.Errorf("occurrence %d is in %s, want %s", i, got[i].Location(), tt.dtstart.Location())
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrencesLimits(t *testing.T) {
+	dtstart := mustParse("2025-01-01T10:00:00Z")
+
+	tests := []struct {
+		name string
+		rule string
+		want int
+	}{
+		{"open-ended daily stops at the maximum", "FREQ=DAILY", MaxOccurrences},
+		{"count above the maximum", "FREQ=DAILY;COUNT=1000", MaxOccurrences},
+		{"open-ended weekly stops at the horizon", "FREQ=WEEKLY", 53},
+		{"open-ended monthly stops at the horizon", "FREQ=MONTHLY", 13},
+		{"weekly count beyond the horizon", "FREQ=WEEKLY;COUNT=100", 100},
+		{"monthly count beyond the horizon", "FREQ=MONTHLY;COUNT=24", 24},
+		{"until beyond the horizon", "FREQ=WEEKLY;UNTIL=20300101", 261},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q) failed: %v", tt.rule, err)
+			}
+			got := rule.Occurrences(dtstart)
+			if len(got) != tt.want {
+				t.Fatalf("got %d occurrences, want %d", len(got), tt.want)
+			}
+			if last := got[len(got)-1]; rule.Count == 0 && rule.Until == nil && last.After(dtstart.Add(OccurrenceHorizon)) {
+				t.Errorf("last occurrence %s is past the horizon", last.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestExceptionDates(t *testing.T) {
+	newYork := mustLoadLocation("America/New_York")
+	rule, err := ParseRRule("FREQ=WEEKLY;COUNT=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	occurrences := rule.Occurrences(time.Date(2025, 3, 2, 9, 0, 0, 0, newYork))
+
+	tests := []struct {
+		name    string
+		exdates []string // Stored in UTC like EventSeriesException.OriginalStartTime
+		want    []string
+	}{
+		{"none", nil,
+			[]string{"2025-03-02T14:00:00Z", "2025-03-09T13:00:00Z", "2025-03-16T13:00:00Z", "2025-03-23T13:00:00Z"}},
+		{"first and last", []string{"2025-03-02T14:00:00Z", "2025-03-23T13:00:00Z"},
+			[]string{"2025-03-09T13:00:00Z", "2025-03-16T13:00:00Z"}},
+		{"after spring forward", []string{"2025-03-09T13:00:00Z"},
+			[]string{"2025-03-02T14:00:00Z", "2025-03-16T13:00:00Z", "2025-03-23T13:00:00Z"}},
+		// An exception at the standard time offset is an hour off the occurrence and excludes nothing
+		{"wrong offset", []string{"2025-03-09T14:00:00Z"},
+			[]string{"2025-03-02T14:00:00Z", "2025-03-09T13:00:00Z", "2025-03-16T13:00:00Z", "2025-03-23T13:00:00Z"}},
+		{"not an occurrence", []string{"2025-03-10T13:00:00Z"},
+			[]string{"2025-03-02T14:00:00Z", "2025-03-09T13:00:00Z", "2025-03-16T13:00:00Z", "2025-03-23T13:00:00Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exdates := make([]time.Time, 0, len(tt.exdates))
+			for _, exdate := range tt.exdates {
+				exdates = append(exdates, mustParse(exdate))
+			}
+			var got []time.Time
+			for _, occurrence := range occurrences {
+				if !ContainsTime(exdates, occurrence) {
+					got = append(got, occurrence)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+			}
+			for i, want := range tt.want {
+				if !got[i].Equal(mustParse(want)) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].UTC().Format(time.RFC3339), want)
+				}
+			}
+		})
+	}
+}
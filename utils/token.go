@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// GenerateSecureToken generates a random hex token of n bytes, suitable for secret URLs
+func GenerateSecureToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Letters and digits that can't be mistaken for one another when read aloud or off a screen
+const readableAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateReadableCode generates a random code of the given length that people can easily type in
+func GenerateReadableCode(length int) (string, error) {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = readableAlphabet[int(b)%len(readableAlphabet)]
+	}
+	return string(buf), nil
+}